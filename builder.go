@@ -0,0 +1,140 @@
+package symdiff
+
+import (
+	"fmt"
+)
+
+/*
+   Builders for constructing expressions without going through S expressions
+
+   3 x^2 + a x - 1 ==>
+
+     x2, _ := Pow("x", Integer(2))
+     t1, _ := Mul(Const(Integer(3)), x2)
+     x, _ := Var("x")
+     a, _ := Param("a")
+     t2, _ := Mul(a, x)
+     poly, _ := Sum(t1, t2, Const(Integer(-1)))
+
+   Builders enforce the same rules as parsing and produce exactly the expression
+   that parsing the printed result gives back.
+*/
+
+// Constant expression
+func Const(c Rational) PolyExp {
+	return PolyExp{e: &ConstantExp{c: c}}
+}
+
+// Variable x as the monomial ( ^ x 1 )
+func Var(x Symbol) (PolyExp, error) {
+	return Pow(x, Integer(1))
+}
+
+// Bare parameter symbol, treated as a constant by differentiation
+func Param(a Symbol) (PolyExp, error) {
+	if err := checkSymbol(a); err != nil {
+		return PolyExp{}, err
+	}
+	return PolyExp{e: &ParamExp{a: a}}, nil
+}
+
+// Monomial ( ^ x n )
+func Pow(x Symbol, n Rational) (PolyExp, error) {
+	if err := checkSymbol(x); err != nil {
+		return PolyExp{}, err
+	}
+	return PolyExp{e: &MonomialExp{x: x, n: n}}, nil
+}
+
+// Monomial with a symbolic exponent ( ^ x e ), e must be a coefficient not mentioning x
+func SymbolicPow(x Symbol, e PolyExp) (PolyExp, error) {
+	if err := checkSymbol(x); err != nil {
+		return PolyExp{}, err
+	}
+	if err := e.check(); err != nil {
+		return PolyExp{}, fmt.Errorf("%s, invalid exponent of monomial in %s", err, x)
+	}
+	if e.IsConstant() {
+		return Pow(x, e.constant().c)
+	}
+	if !e.isCoefficient() {
+		return PolyExp{}, fmt.Errorf("exponent %s of monomial in %s may not contain monomials", e.ToSExp().String(), x)
+	}
+	if e.mentions(x) {
+		return PolyExp{}, fmt.Errorf("exponent %s of monomial in %s depends on its own variable", e.ToSExp().String(), x)
+	}
+	return PolyExp{e: &MonomialExp{x: x, e: &e}}, nil
+}
+
+// Power ( ^ b n ) of any expression, powers of parameters are coefficients as when parsing
+func Raise(b PolyExp, n Rational) (PolyExp, error) {
+	if err := b.check(); err != nil {
+		return PolyExp{}, fmt.Errorf("%s, invalid base of power", err)
+	}
+	return PolyExp{e: &PowerExp{b: &b, n: n}}, nil
+}
+
+// Square root ( ^ e 1/2 )
+func Sqrt(e PolyExp) (PolyExp, error) {
+	half, _ := NewRational(1, 2)
+	return Raise(e, half)
+}
+
+// Sum of two or more expressions
+func Sum(terms ...PolyExp) (PolyExp, error) {
+	if len(terms) < 2 {
+		return PolyExp{}, fmt.Errorf("sum needs at least two terms, got %d", len(terms))
+	}
+	for i := range terms {
+		if err := terms[i].check(); err != nil {
+			return PolyExp{}, fmt.Errorf("%s, invalid term %d of sum", err, i)
+		}
+	}
+	return PolyExp{e: &SumExp{ps: append([]PolyExp{}, terms...)}}, nil
+}
+
+// Product of one or more expressions.  A leading coefficient followed by other
+// factors becomes the coefficient of the product, otherwise the coefficient is 1
+func Mul(factors ...PolyExp) (PolyExp, error) {
+	if len(factors) < 1 {
+		return PolyExp{}, fmt.Errorf("product needs at least one factor")
+	}
+	for i := range factors {
+		if err := factors[i].check(); err != nil {
+			return PolyExp{}, fmt.Errorf("%s, invalid factor %d of product", err, i)
+		}
+	}
+	coeff := Const(Integer(1))
+	if len(factors) > 1 && factors[0].isCoefficient() {
+		coeff, factors = factors[0], factors[1:]
+	}
+	r := factors[0]
+	prod := ProductExp{l: &coeff, r: &r}
+	if len(factors) > 1 {
+		prod.fs = append([]PolyExp{}, factors[1:]...)
+	}
+	return PolyExp{e: &prod}, nil
+}
+
+// Absolute value ( abs e )
+func Abs(e PolyExp) (PolyExp, error) {
+	if err := e.check(); err != nil {
+		return PolyExp{}, fmt.Errorf("%s, invalid argument of abs", err)
+	}
+	return PolyExp{e: &AbsExp{e: &e}}, nil
+}
+
+// Sign ( sign e )
+func Sign(e PolyExp) (PolyExp, error) {
+	if err := e.check(); err != nil {
+		return PolyExp{}, fmt.Errorf("%s, invalid argument of sign", err)
+	}
+	return PolyExp{e: &SignExp{e: &e}}, nil
+}
+
+func checkSymbol(x Symbol) error {
+	if x == "" || !IsSymbol(string(x)) {
+		return fmt.Errorf("invalid symbol %q, symbols must be alphabetic", x)
+	}
+	return nil
+}
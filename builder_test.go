@@ -0,0 +1,99 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func must(t *testing.T) func(PolyExp, error) PolyExp {
+	return func(poly PolyExp, err error) PolyExp {
+		require.NoError(t, err)
+		return poly
+	}
+}
+
+func TestBuildersMatchParsing(t *testing.T) {
+	b := must(t)
+	half, err := NewRational(1, 2)
+	require.NoError(t, err)
+	x, a := b(Var("x")), b(Param("a"))
+
+	for _, tc := range []struct {
+		built PolyExp
+		raw   string
+	}{
+		{Const(half), "1/2"},
+		{x, "( ^ x 1 )"},
+		{x, "x"},
+		{a, "a"},
+		{b(Pow("x", Integer(-3))), "( ^ x -3 )"},
+		{b(SymbolicPow("x", b(Sum(b(Param("n")), Const(Integer(1)))))), "( ^ x ( + n 1 ) )"},
+		{b(SymbolicPow("x", Const(Integer(4)))), "( ^ x 4 )"},
+		{b(Mul(Const(Integer(3)), b(Pow("x", Integer(2))))), "( * 3 ( ^ x 2 ) )"},
+		{b(Mul(a, x, b(Pow("y", Integer(2))))), "( * a ( ^ x 1 ) ( ^ y 2 ) )"},
+		{b(Mul(x, x)), "( * 1 ( ^ x 1 ) ( ^ x 1 ) )"},
+		{b(Sum(b(Mul(a, x)), Const(Integer(-1)))), "( + ( * a ( ^ x 1 ) ) -1 )"},
+		{b(Raise(b(Sum(x, Const(Integer(1)))), Integer(3))), "( ^ ( + ( ^ x 1 ) 1 ) 3 )"},
+		{b(Raise(a, Integer(2))), "( ^ a 2 )"},
+		{b(Sqrt(b(Sum(b(Pow("x", Integer(2))), Const(Integer(1)))))), "( sqrt ( + ( ^ x 2 ) 1 ) )"},
+		{b(Abs(x)), "( abs ( ^ x 1 ) )"},
+		{b(Sign(b(Sum(x, a)))), "( sign ( + ( ^ x 1 ) a ) )"},
+	} {
+		assert.Equal(t, polyFromString(t, tc.raw, "a", "n"), tc.built, tc.raw)
+	}
+}
+
+func TestBuildersValidate(t *testing.T) {
+	b := must(t)
+	x := b(Var("x"))
+
+	_, err := Var("x1")
+	assert.Error(t, err)
+	_, err = Var("")
+	assert.Error(t, err)
+	_, err = Param("+")
+	assert.Error(t, err)
+	_, err = Pow("", Integer(2))
+	assert.Error(t, err)
+
+	// exponents are coefficients without their own variable
+	_, err = SymbolicPow("x", x)
+	assert.Error(t, err)
+	_, err = SymbolicPow("x", b(Param("x")))
+	assert.Error(t, err)
+
+	// sums need two terms, products one factor
+	_, err = Sum(x)
+	assert.Error(t, err)
+	_, err = Mul()
+	assert.Error(t, err)
+
+	// the zero value is not an expression
+	_, err = Sum(x, PolyExp{})
+	assert.Error(t, err)
+	_, err = Mul(PolyExp{})
+	assert.Error(t, err)
+	_, err = Raise(PolyExp{}, Integer(2))
+	assert.Error(t, err)
+	_, err = Abs(PolyExp{})
+	assert.Error(t, err)
+	_, err = Sign(PolyExp{})
+	assert.Error(t, err)
+}
+
+func TestBuiltExpressionsDifferentiate(t *testing.T) {
+	b := must(t)
+	poly := b(Sum(
+		b(Mul(Const(Integer(3)), b(Pow("x", Integer(2))))),
+		b(Mul(b(Param("a")), b(Var("x")))),
+		Const(Integer(-1)),
+	))
+	derivative, err := Differentiate("x", poly)
+	require.NoError(t, err)
+	simplified, err := Simplify(*derivative)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 6 ( ^ x 1 ) ) a )", simplified.ToSExp().String())
+}
@@ -0,0 +1,138 @@
+package symdiff
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Number of results kept by a cache made with a non positive size
+const DefaultCacheSize = 4096
+
+// Memoizing front for Differentiate and Simplify keyed on the structural
+// hash of the input and the variable differentiated in.  The least recently
+// used result is evicted once size results are kept.
+// Results are shared between callers, which is safe as expressions are never
+// modified.  Safe for concurrent use, concurrent misses on the same input may
+// compute it more than once.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[cacheKey]*list.Element
+	// most recently used first
+	lru   *list.List
+	stats CacheStats
+}
+
+type cacheKey struct {
+	op   string
+	v    Symbol
+	hash uint64
+}
+
+type cacheEntry struct {
+	key cacheKey
+	// checked on lookup to rule out hash collisions
+	in  PolyExp
+	out PolyExp
+}
+
+// Counts of cache lookups since the cache was made or cleared
+type CacheStats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+	// results currently kept
+	Size int
+}
+
+func NewCache(size int) *Cache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &Cache{
+		size:    size,
+		entries: make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Memoized Differentiate
+func (c *Cache) Differentiate(v Symbol, exp PolyExp) (*PolyExp, error) {
+	return c.memo("d", v, exp, func() (*PolyExp, error) {
+		return Differentiate(v, exp)
+	})
+}
+
+// Memoized Simplify
+func (c *Cache) Simplify(exp PolyExp) (*PolyExp, error) {
+	return c.memo("s", "", exp, func() (*PolyExp, error) {
+		return Simplify(exp)
+	})
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := c.stats
+	ret.Size = c.lru.Len()
+	return ret
+}
+
+// Drop all results and reset statistics
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[cacheKey]*list.Element)
+	c.lru.Init()
+	c.stats = CacheStats{}
+}
+
+// Result of f for op on exp, computed by f on a miss.  Errors are not cached.
+func (c *Cache) memo(op string, v Symbol, exp PolyExp, f func() (*PolyExp, error)) (*PolyExp, error) {
+	if err := exp.check(); err != nil {
+		return nil, err
+	}
+	key := cacheKey{op: op, v: v, hash: exp.Hash()}
+	if out, ok := c.lookup(key, exp); ok {
+		return &out, nil
+	}
+	out, err := f()
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, exp, *out)
+	return out, nil
+}
+
+func (c *Cache) lookup(key cacheKey, exp PolyExp) (PolyExp, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		if entry.in.Equal(&exp) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			return entry.out, true
+		}
+	}
+	c.stats.Misses++
+	return PolyExp{}, false
+}
+
+func (c *Cache) store(key cacheKey, in, out PolyExp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		// computed concurrently or colliding, keep the latest
+		el.Value = &cacheEntry{key: key, in: in, out: out}
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, in: in, out: out})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+}
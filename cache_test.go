@@ -0,0 +1,95 @@
+package symdiff_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestCache(t *testing.T) {
+	c := NewCache(0)
+	raw := "( * 1 ( ^ ( + ( ^ x 2 ) b ) 3 ) ( abs ( ^ x 1 ) ) )"
+	poly := polyFromString(t, raw, "b")
+	expected, err := Differentiate("x", poly)
+	require.NoError(t, err)
+
+	d, err := c.Differentiate("x", poly)
+	require.NoError(t, err)
+	assert.Equal(t, expected, d)
+	// equal input parsed separately hits
+	d, err = c.Differentiate("x", polyFromString(t, raw, "b"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, d)
+	// the variable is part of the key, both derivatives miss
+	seven := polyFromString(t, "7")
+	_, err = c.Differentiate("a", seven)
+	require.NoError(t, err)
+	_, err = c.Differentiate("b", seven)
+	require.NoError(t, err)
+
+	s, err := c.Simplify(*d)
+	require.NoError(t, err)
+	expectedS, err := Simplify(*d)
+	require.NoError(t, err)
+	assert.Equal(t, expectedS, s)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 4, Size: 4}, c.Stats())
+
+	// errors are not cached
+	_, err = c.Simplify(PolyExp{})
+	assert.Error(t, err)
+	_, err = c.Differentiate("x", polyFromString(t, "( * x ( ^ x 2 ) )", "x"))
+	assert.Error(t, err)
+	assert.Equal(t, 4, c.Stats().Size)
+
+	c.Clear()
+	assert.Equal(t, CacheStats{}, c.Stats())
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := NewCache(2)
+	x1 := polyFromString(t, "( ^ x 1 )")
+	x2 := polyFromString(t, "( ^ x 2 )")
+	x3 := polyFromString(t, "( ^ x 3 )")
+	for _, p := range []PolyExp{x1, x2, x1, x3, x1, x2} {
+		_, err := c.Simplify(p)
+		require.NoError(t, err)
+	}
+	// x2 is evicted by x3 as x1 was used more recently, then x3 by x2
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 4, Evictions: 2, Size: 2}, c.Stats())
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	c := NewCache(8)
+	polys := []PolyExp{
+		polyFromString(t, "( ^ ( + ( ^ x 1 ) 1 ) 5 )"),
+		polyFromString(t, "( * a ( ^ x 3 ) )", "a"),
+		polyFromString(t, hinge),
+	}
+	expected := make([]string, len(polys))
+	for i, p := range polys {
+		d, err := Differentiate("x", p)
+		require.NoError(t, err)
+		expected[i] = d.ToSExp().String()
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				k := i % len(polys)
+				d, err := c.Differentiate("x", polys[k])
+				if assert.NoError(t, err) {
+					assert.Equal(t, expected[k], d.ToSExp().String())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	st := c.Stats()
+	assert.Equal(t, 800, st.Hits+st.Misses)
+	assert.Equal(t, len(polys), st.Size)
+}
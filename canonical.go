@@ -0,0 +1,148 @@
+package symdiff
+
+import (
+	"sort"
+	"strings"
+)
+
+/*
+   Canonical normal form
+
+   Expressions with the same canonical form are equal as polynomials over their
+   variables and parameters, up to what simplification can prove.
+
+   - the expression is simplified, see Simplify
+   - products of sums and powers of sums with positive integer exponents are
+     expanded, ( ^ ( + ( ^ x 1 ) 1 ) 2 ) ==> ( + ( * 2 ( ^ x 1 ) ) ( ^ x 2 ) 1 )
+   - factors of products are flattened, powers of the same variable are
+     multiplied out and factors are sorted, monomials first by symbol and
+     exponent as in Fold, then other factors by printed form
+     ( * 2 ( ^ y 1 ) ( ^ x 2 ) ( ^ x 1 ) ) ==> ( * 2 ( ^ x 3 ) ( ^ y 1 ) )
+   - terms with the same factors are merged adding their coefficients
+   - terms are ordered by their factors, parameter terms and the constant last
+   - sub expressions of powers, absolute values, signs and piecewise
+     expressions are canonical
+*/
+
+func Canonicalize(poly PolyExp) (*PolyExp, error) {
+	s, err := Simplify(poly)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := expandTerm(*s, canonicalFactor)
+	if err != nil {
+		return nil, err
+	}
+	return joinTerms(collectTerms(ts)), nil
+}
+
+// Structural equality, use Canonicalize first to compare normal forms.
+// Unpopulated expressions are only equal to each other.
+func (p *PolyExp) Equal(q *PolyExp) bool {
+	if p.check() != nil || q.check() != nil {
+		return p.check() != nil && q.check() != nil
+	}
+	ps, qs := p.ToSExp(), q.ToSExp()
+	return ps.Equal(&qs)
+}
+
+// Compound with canonical sub expressions
+func canonicalFactor(f PolyExp) (PolyExp, error) {
+	children := f.e.children()
+	for i := range children {
+		c, err := Canonicalize(children[i])
+		if err != nil {
+			return PolyExp{}, err
+		}
+		children[i] = *c
+	}
+	e, err := f.e.rebuild(children)
+	if err != nil {
+		return PolyExp{}, err
+	}
+	return normalizeCompound(PolyExp{e: e}), nil
+}
+
+// Multiply monomials in the same variable and sort factors, monomials with
+// exponent 0 are dropped
+func mergePowers(factors []PolyExp) []PolyExp {
+	exponents := make(map[Symbol][]PolyExp)
+	ret := make([]PolyExp, 0, len(factors))
+	for _, f := range factors {
+		if f.IsMon() {
+			m := f.mon()
+			exponents[m.x] = append(exponents[m.x], m.Exponent())
+			continue
+		}
+		ret = append(ret, f)
+	}
+	for x, ns := range exponents {
+		mon := MonomialExp{x: x, n: Integer(0)}
+		if len(ns) == 1 {
+			mon = *factors[indexOfMon(factors, x)].mon()
+		} else {
+			n, _ := Sum(ns...)
+			mon = normalizeExponent(MonomialExp{x: x, e: &n})
+		}
+		if mon.e == nil && mon.n.IsZero() {
+			continue
+		}
+		ret = append(ret, PolyExp{e: &mon})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return lessFactor(ret[i], ret[j])
+	})
+	return ret
+}
+
+func indexOfMon(factors []PolyExp, x Symbol) int {
+	for i := range factors {
+		if factors[i].IsMon() && factors[i].mon().x == x {
+			return i
+		}
+	}
+	return -1
+}
+
+// Monomials by symbol then numeric exponents ascending then symbolic
+// exponents, followed by other factors by printed form
+func lessFactor(f, g PolyExp) bool {
+	if f.IsMon() != g.IsMon() {
+		return f.IsMon()
+	}
+	if !f.IsMon() {
+		return f.ToSExp().String() < g.ToSExp().String()
+	}
+	m, n := f.mon(), g.mon()
+	if m.x != n.x {
+		return m.x < n.x
+	}
+	if m.e == nil && n.e == nil {
+		return m.n.Less(n.n)
+	}
+	if m.e == nil || n.e == nil {
+		return m.e == nil
+	}
+	return exponentKey(*m) < exponentKey(*n)
+}
+
+// Lexicographic order of sorted factor lists
+func lessFactors(fs, gs []PolyExp) bool {
+	for i := 0; i < len(fs) && i < len(gs); i++ {
+		if lessFactor(fs[i], gs[i]) {
+			return true
+		}
+		if lessFactor(gs[i], fs[i]) {
+			return false
+		}
+	}
+	return len(fs) < len(gs)
+}
+
+func factorsKey(factors []PolyExp) string {
+	keys := make([]string, len(factors))
+	for i := range factors {
+		keys[i] = factors[i].ToSExp().String()
+	}
+	return strings.Join(keys, " ")
+}
@@ -0,0 +1,65 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func canonicalString(t *testing.T, raw string, params ...Symbol) string {
+	c, err := Canonicalize(polyFromString(t, raw, params...))
+	require.NoError(t, err)
+	return c.ToSExp().String()
+}
+
+func TestCanonicalize(t *testing.T) {
+	cases := []struct {
+		raw, expected string
+	}{
+		{"( + 1 ( ^ x 2 ) ( ^ x 1 ) )", "( + ( ^ x 1 ) ( ^ x 2 ) 1 )"},
+		{"( * 2 ( ^ y 1 ) ( ^ x 2 ) ( ^ x 1 ) )", "( * 2 ( ^ x 3 ) ( ^ y 1 ) )"},
+		{"( + ( * 2 ( ^ x 1 ) ( ^ y 1 ) ) ( * 3 ( ^ y 1 ) ( ^ x 1 ) ) )", "( * 5 ( ^ x 1 ) ( ^ y 1 ) )"},
+		{"( + ( * 2 ( ^ x 1 ) ( ^ y 1 ) ) ( * -2 ( ^ y 1 ) ( ^ x 1 ) ) 1 )", "1"},
+		{"( * 1 ( ^ x 1 ) ( ^ x -1 ) )", "1"},
+		{"( * a ( ^ y 1 ) ( * 2 ( ^ x 1 ) ) )", "( * ( * 2 a ) ( ^ x 1 ) ( ^ y 1 ) )"},
+		{"( abs ( * 1 ( ^ y 1 ) ( ^ x 1 ) ) )", "( abs ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) )"},
+		{"( + ( ^ x 1 ) 0 )", "( ^ x 1 )"},
+		{"( + a ( * -1 a ) )", "0"},
+		{"( ^ ( + ( ^ x 1 ) 1 ) 2 )", "( + ( * 2 ( ^ x 1 ) ) ( ^ x 2 ) 1 )"},
+		{"( * 1 ( + ( ^ x 1 ) 1 ) ( + ( ^ y 1 ) -1 ) )", "( + ( * -1 ( ^ x 1 ) ) ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( ^ y 1 ) -1 )"},
+		{"( ^ ( + ( ^ x 1 ) 1 ) -1 )", "( ^ ( + ( ^ x 1 ) 1 ) -1 )"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, canonicalString(t, c.raw, "a"), c.raw)
+		// idempotent
+		assert.Equal(t, c.expected, canonicalString(t, c.expected, "a"), c.expected)
+	}
+}
+
+func TestCanonicalizeOrderIndependent(t *testing.T) {
+	equivalent := []string{
+		"( + ( * 1 ( ^ x 1 ) ( ^ y 2 ) ) ( ^ x 2 ) 3 ( abs ( + ( ^ y 1 ) ( ^ x 1 ) ) ) )",
+		"( + ( abs ( + ( ^ x 1 ) ( ^ y 1 ) ) ) 3 ( * 1 ( ^ y 2 ) ( ^ x 1 ) ) ( ^ x 2 ) )",
+		"( + 1 ( ^ x 2 ) ( + 2 ( * 1 ( ^ y 1 ) ( ^ x 1 ) ( ^ y 1 ) ) ) ( abs ( + ( ^ y 1 ) 0 ( ^ x 1 ) ) ) )",
+	}
+	first, err := Canonicalize(polyFromString(t, equivalent[0]))
+	require.NoError(t, err)
+	for _, raw := range equivalent[1:] {
+		c, err := Canonicalize(polyFromString(t, raw))
+		require.NoError(t, err)
+		assert.True(t, first.Equal(c), "%s != %s", first.ToSExp().String(), c.ToSExp().String())
+	}
+}
+
+func TestPolyExpEqual(t *testing.T) {
+	p := polyFromString(t, "( + ( ^ x 2 ) 1 )")
+	q := polyFromString(t, "( + ( ^ x 2 ) 1 )")
+	r := polyFromString(t, "( + 1 ( ^ x 2 ) )")
+	assert.True(t, p.Equal(&p))
+	assert.True(t, p.Equal(&q))
+	// structural, not up to reordering
+	assert.False(t, p.Equal(&r))
+	assert.False(t, p.Equal(&PolyExp{}))
+}
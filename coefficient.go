@@ -0,0 +1,180 @@
+package symdiff
+
+import (
+	"sort"
+	"strings"
+)
+
+// Product of a rational and powers of parameters, k * a^n * b^m * ...
+// Coefficient expressions expand into sums of these terms
+type coeffTerm struct {
+	k Rational
+	// sorted by parameter, exponents are non zero
+	params []paramPower
+}
+
+// Parameter raised to a rational power, a^1 is the parameter itself
+type paramPower struct {
+	a Symbol
+	n Rational
+}
+
+func (p paramPower) toPoly() PolyExp {
+	if p.n.Equal(Integer(1)) {
+		return PolyExp{e: &ParamExp{a: p.a}}
+	}
+	return PolyExp{e: &PowerExp{b: &PolyExp{e: &ParamExp{a: p.a}}, n: p.n}}
+}
+
+func (t coeffTerm) key() string {
+	names := make([]string, len(t.params))
+	for i, p := range t.params {
+		names[i] = string(p.a)
+		if !p.n.Equal(Integer(1)) {
+			names[i] += "^" + p.n.String()
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+func (t coeffTerm) toPoly() PolyExp {
+	if len(t.params) == 0 {
+		return PolyExp{e: &ConstantExp{c: t.k}}
+	}
+	// ( * a ( * b c ) )
+	ret := t.params[len(t.params)-1].toPoly()
+	for i := len(t.params) - 2; i >= 0; i-- {
+		r := ret
+		l := t.params[i].toPoly()
+		ret = PolyExp{
+			e: &ProductExp{
+				l: &l,
+				r: &r,
+			},
+		}
+	}
+	if t.k.Equal(Integer(1)) {
+		return ret
+	}
+	return PolyExp{
+		e: &ProductExp{
+			l: &PolyExp{e: &ConstantExp{c: t.k}},
+			r: &ret,
+		},
+	}
+}
+
+// Invariant: coeff.isCoefficient()
+func expandCoefficient(coeff PolyExp) []coeffTerm {
+	if coeff.IsConstant() {
+		return []coeffTerm{{k: coeff.constant().c}}
+	}
+	if coeff.IsParam() {
+		return []coeffTerm{{k: Integer(1), params: []paramPower{{a: coeff.param().a, n: Integer(1)}}}}
+	}
+	if coeff.IsPower() {
+		if coeff.power().n.IsZero() {
+			return []coeffTerm{{k: Integer(1)}}
+		}
+		return []coeffTerm{{k: Integer(1), params: []paramPower{{a: coeff.power().b.param().a, n: coeff.power().n}}}}
+	}
+	if coeff.IsProduct() {
+		terms := multiplyCoefficients(expandCoefficient(*coeff.product().l), expandCoefficient(*coeff.product().r))
+		for _, f := range coeff.product().fs {
+			terms = multiplyCoefficients(terms, expandCoefficient(f))
+		}
+		return terms
+	}
+	terms := make([]coeffTerm, 0)
+	for _, p := range coeff.sum().ps {
+		terms = append(terms, expandCoefficient(p)...)
+	}
+	return terms
+}
+
+func multiplyCoefficients(l, r []coeffTerm) []coeffTerm {
+	terms := make([]coeffTerm, 0, len(l)*len(r))
+	for _, lt := range l {
+		for _, rt := range r {
+			terms = append(terms, coeffTerm{k: lt.k.Mul(rt.k), params: multiplyParams(lt.params, rt.params)})
+		}
+	}
+	return terms
+}
+
+// Merge two sorted products of parameter powers adding the exponents of
+// shared parameters, cancelled parameters are dropped
+func multiplyParams(l, r []paramPower) []paramPower {
+	params := make([]paramPower, 0, len(l)+len(r))
+	i, j := 0, 0
+	for i < len(l) || j < len(r) {
+		switch {
+		case j == len(r) || (i < len(l) && l[i].a < r[j].a):
+			params = append(params, l[i])
+			i++
+		case i == len(l) || r[j].a < l[i].a:
+			params = append(params, r[j])
+			j++
+		default:
+			if n := l[i].n.Add(r[j].n); !n.IsZero() {
+				params = append(params, paramPower{a: l[i].a, n: n})
+			}
+			i++
+			j++
+		}
+	}
+	return params
+}
+
+// Sum of coefficient terms keyed by their parameters
+type coefficient map[string]coeffTerm
+
+func (c coefficient) add(terms []coeffTerm) {
+	for _, t := range terms {
+		key := t.key()
+		if prev, ok := c[key]; ok {
+			t.k = t.k.Add(prev.k)
+		}
+		c[key] = t
+	}
+}
+
+// Terms of the coefficient ordered by parameters with the constant term
+// last.  Cancelled terms are dropped.
+func (c coefficient) terms() []PolyExp {
+	keys := make([]string, 0, len(c))
+	for key, t := range c {
+		if !t.k.IsZero() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i] == "" || keys[j] == "" {
+			return keys[i] != "" && keys[j] == ""
+		}
+		return keys[i] < keys[j]
+	})
+	terms := make([]PolyExp, len(keys))
+	for i, key := range keys {
+		terms[i] = c[key].toPoly()
+	}
+	return terms
+}
+
+// Terms of coefficient * poly, unit coefficients are dropped and a
+// cancelled coefficient drops the term entirely
+func (c coefficient) times(poly PolyExp) []PolyExp {
+	a := c.terms()
+	if len(a) == 0 {
+		return nil
+	}
+	if len(a) == 1 && a[0].IsConstant() && a[0].constant().c.Equal(Integer(1)) {
+		return []PolyExp{poly}
+	}
+	return []PolyExp{{
+		e: &ProductExp{
+			l: Join(a),
+			r: &poly,
+		},
+	}}
+}
@@ -0,0 +1,126 @@
+package symdiff
+
+import (
+	"sort"
+)
+
+/*
+   Collecting by a variable
+
+   The expression is expanded and its terms are grouped by their power of the
+   chosen variable, the remaining factors of each group adding up to the
+   coefficient of that power.
+
+     ( + ( * 1 ( ^ a 1 ) ( ^ x 2 ) ) ( * 1 ( ^ b 1 ) ( ^ x 2 ) ) ( * 1 ( ^ c 1 ) ( ^ x 1 ) ) )
+       ==> ( + ( * 1 ( ^ c 1 ) ( ^ x 1 ) ) ( * 1 ( + ( ^ a 1 ) ( ^ b 1 ) ) ( ^ x 2 ) ) )
+
+   Powers are ordered by increasing exponent, followed by terms mentioning the
+   variable other than through a numeric power, e.g. in abs or symbolic
+   exponents, which are kept as they are, and the terms free of the variable.
+*/
+
+// Sum over powers of v with coefficients in the other symbols
+func Collect(poly PolyExp, v Symbol) (*PolyExp, error) {
+	if err := poly.check(); err != nil {
+		return nil, err
+	}
+	powers, kept, free, err := collectPowers(poly, v)
+	if err != nil {
+		return nil, err
+	}
+	var ret []PolyExp
+	for _, p := range powers {
+		ret = append(ret, p.toPolyExp(v))
+	}
+	for _, t := range append(kept, free...) {
+		ret = append(ret, Flatten(*joinTerms([]expandedTerm{t}))...)
+	}
+	if len(ret) == 0 {
+		ret = append(ret, Zero())
+	}
+	return Join(ret), nil
+}
+
+// Terms of an expansion with the same power of a variable
+type collectedPower struct {
+	n Rational
+	// the terms divided by the power
+	terms []expandedTerm
+}
+
+// Expanded terms grouped by their power of v in increasing order, followed by
+// the terms mentioning v otherwise and the terms free of v
+func collectPowers(poly PolyExp, v Symbol) ([]collectedPower, []expandedTerm, []expandedTerm, error) {
+	ts, err := expandTerm(poly, func(f PolyExp) (PolyExp, error) {
+		return f, nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var powers []collectedPower
+	var kept, free []expandedTerm
+	for _, t := range collectTerms(ts) {
+		i, ok := collectible(t, v)
+		switch {
+		case !ok:
+			kept = append(kept, t)
+		case i < 0:
+			free = append(free, t)
+		default:
+			n := t.factors[i].mon().n
+			rest := expandedTerm{a: t.a, factors: append(append([]PolyExp{}, t.factors[:i]...), t.factors[i+1:]...)}
+			j := 0
+			for j < len(powers) && !powers[j].n.Equal(n) {
+				j++
+			}
+			if j == len(powers) {
+				powers = append(powers, collectedPower{n: n})
+			}
+			powers[j].terms = append(powers[j].terms, rest)
+		}
+	}
+	sort.Slice(powers, func(i, j int) bool {
+		return powers[i].n.Less(powers[j].n)
+	})
+	return powers, kept, free, nil
+}
+
+// Coefficient times the power, the coefficient as a sum when it has several
+// terms
+func (p collectedPower) toPolyExp(v Symbol) PolyExp {
+	mon := PolyExp{e: &MonomialExp{x: v, n: p.n}}
+	if len(p.terms) == 1 {
+		t := p.terms[0]
+		return *joinTerms([]expandedTerm{{a: t.a, factors: mergePowers(append(t.factors, mon))}})
+	}
+	return PolyExp{
+		e: &ProductExp{
+			l:  &PolyExp{e: &ConstantExp{c: Integer(1)}},
+			r:  joinTerms(collectTerms(p.terms)),
+			fs: []PolyExp{mon},
+		},
+	}
+}
+
+// Index of the power of v among the factors of an expanded term, -1 when the
+// term is free of v and false when v appears elsewhere
+func collectible(t expandedTerm, v Symbol) (int, bool) {
+	for _, a := range t.a {
+		for _, p := range a.params {
+			if p.a == v {
+				return 0, false
+			}
+		}
+	}
+	ret := -1
+	for i, f := range t.factors {
+		if f.IsMon() && f.mon().x == v && f.mon().e == nil {
+			ret = i
+			continue
+		}
+		if f.e.mentions(v) {
+			return 0, false
+		}
+	}
+	return ret, true
+}
@@ -0,0 +1,32 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestCollect(t *testing.T) {
+	cases := []struct {
+		raw, expected string
+	}{
+		{"( + ( * 1 ( ^ a 1 ) ( ^ x 2 ) ) ( * 1 ( ^ b 1 ) ( ^ x 2 ) ) ( * 1 ( ^ c 1 ) ( ^ x 1 ) ) )", "( + ( * 1 ( ^ c 1 ) ( ^ x 1 ) ) ( * 1 ( + ( ^ a 1 ) ( ^ b 1 ) ) ( ^ x 2 ) ) )"},
+		{"( * 1 ( + ( ^ x 1 ) ( ^ y 1 ) ) ( + ( ^ x 1 ) 1 ) )", "( + ( * 1 ( + ( ^ y 1 ) 1 ) ( ^ x 1 ) ) ( ^ x 2 ) ( ^ y 1 ) )"},
+		{"( + ( * 2 ( ^ x 1 ) ) ( * 3 ( ^ x 1 ) ) ( ^ y 2 ) 1 )", "( + ( * 5 ( ^ x 1 ) ) ( ^ y 2 ) 1 )"},
+		// terms mentioning x other than through a power are kept
+		{"( + ( abs ( ^ x 1 ) ) ( * 1 ( ^ y 1 ) ( ^ x -1 ) ) ( ^ y 1 ) )", "( + ( * 1 ( ^ x -1 ) ( ^ y 1 ) ) ( abs ( ^ x 1 ) ) ( ^ y 1 ) )"},
+		{"( + ( ^ y 1 ) ( * -1 ( ^ y 1 ) ) )", "0"},
+		{"( ^ ( + ( ^ x 1 ) ( ^ y 1 ) ) 2 )", "( + ( * 2 ( ^ x 1 ) ( ^ y 1 ) ) ( ^ x 2 ) ( ^ y 2 ) )"},
+	}
+	for _, c := range cases {
+		poly := polyFromString(t, c.raw)
+		out, err := Collect(poly, "x")
+		require.NoError(t, err, c.raw)
+		assert.Equal(t, c.expected, out.ToSExp().String(), c.raw)
+		eq, err := Equivalent(poly, *out)
+		require.NoError(t, err)
+		assert.True(t, eq.Equivalent, c.raw)
+	}
+}
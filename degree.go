@@ -0,0 +1,147 @@
+package symdiff
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+   Inspecting polynomials
+
+   Degrees and coefficients are read off the expansion of an expression, see
+   Expand and Collect, so ( * 1 ( + ( ^ x 1 ) 1 ) ( + ( ^ x 1 ) -1 ) ) has
+   degree 2 in x.  Coefficients of powers of a variable may be expressions in
+   the other symbols and parameters.
+
+   The expression must be a polynomial in the variable asked about, only
+   natural powers of it and no compound terms such as abs or powers of sums
+   mentioning it.  The zero polynomial has degree -1.
+*/
+
+// Degree in v
+func (p *PolyExp) Degree(v Symbol) (int, error) {
+	powers, free, err := polynomialIn(*p, v)
+	if err != nil {
+		return 0, err
+	}
+	if len(powers) > 0 {
+		return powers[len(powers)-1].n.Int(), nil
+	}
+	if len(free) > 0 {
+		return 0, nil
+	}
+	return -1, nil
+}
+
+// Largest sum of the exponents of the variables in a term, parameters are
+// coefficients and do not count
+func (p *PolyExp) TotalDegree() (int, error) {
+	if err := p.check(); err != nil {
+		return 0, err
+	}
+	ts, err := expandTerm(*p, func(f PolyExp) (PolyExp, error) {
+		return f, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	ret := -1
+	for _, t := range collectTerms(ts) {
+		n := 0
+		for _, f := range t.factors {
+			if !f.IsMon() || f.mon().e != nil || !f.mon().n.IsInteger() || f.mon().n.Sign() < 0 {
+				return 0, fmt.Errorf("term %s is not a product of natural powers of variables", joinTerms([]expandedTerm{t}).ToSExp().String())
+			}
+			n += f.mon().n.Int()
+		}
+		if n > ret {
+			ret = n
+		}
+	}
+	return ret, nil
+}
+
+// Term of the highest power of v with its coefficient, the whole expression
+// when it is free of v
+func (p *PolyExp) LeadingTerm(v Symbol) (*PolyExp, error) {
+	powers, free, err := polynomialIn(*p, v)
+	if err != nil {
+		return nil, err
+	}
+	if len(powers) > 0 {
+		ret := powers[len(powers)-1].toPolyExp(v)
+		return &ret, nil
+	}
+	return joinTerms(free), nil
+}
+
+// Coefficient of v^n, 0 when there is no such term
+func (p *PolyExp) Coefficient(v Symbol, n int) (*PolyExp, error) {
+	powers, free, err := polynomialIn(*p, v)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return joinTerms(free), nil
+	}
+	for _, pow := range powers {
+		if pow.n.Equal(Integer(n)) {
+			return joinTerms(collectTerms(pow.terms)), nil
+		}
+	}
+	ret := Zero()
+	return &ret, nil
+}
+
+// Rational coefficients of the powers of v, the coefficient of v^n at index n
+// and none for the zero polynomial
+func (p *PolyExp) Coefficients(v Symbol) ([]Rational, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	ret, err := toUnivariateIn(*p, v)
+	if err != nil {
+		return nil, fmt.Errorf("%s, coefficients must be rational", err)
+	}
+	return []Rational(ret), nil
+}
+
+// Sorted symbols of the monomials, parameters are not variables
+func (p *PolyExp) Variables() []Symbol {
+	if p.check() != nil {
+		return nil
+	}
+	seen := make(map[Symbol]bool)
+	Inspect(*p, func(e PolyExp) bool {
+		if e.IsMon() {
+			seen[e.mon().x] = true
+		}
+		return true
+	})
+	ret := make([]Symbol, 0, len(seen))
+	for s := range seen {
+		ret = append(ret, s)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// Powers of v and the terms free of v of a polynomial in v
+func polynomialIn(poly PolyExp, v Symbol) ([]collectedPower, []expandedTerm, error) {
+	if err := poly.check(); err != nil {
+		return nil, nil, err
+	}
+	powers, kept, free, err := collectPowers(poly, v)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(kept) > 0 {
+		return nil, nil, fmt.Errorf("term %s is not a polynomial in %s", joinTerms(kept[:1]).ToSExp().String(), v)
+	}
+	for _, pow := range powers {
+		if !pow.n.IsInteger() || pow.n.Sign() < 0 {
+			return nil, nil, fmt.Errorf("power %s of %s is not natural", pow.n.String(), v)
+		}
+	}
+	return powers, free, nil
+}
@@ -0,0 +1,81 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestDegree(t *testing.T) {
+	// ( a + b ) x^2 y + c x + y^3 + 4
+	poly := polyFromString(t, "( + ( * 1 ( + ( ^ a 1 ) ( ^ b 1 ) ) ( ^ x 2 ) ( ^ y 1 ) ) ( * 1 ( ^ c 1 ) ( ^ x 1 ) ) ( ^ y 3 ) 4 )")
+	assert.Equal(t, []Symbol{"a", "b", "c", "x", "y"}, poly.Variables())
+
+	n, err := poly.Degree("x")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = poly.Degree("y")
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	n, err = poly.Degree("z")
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	n, err = poly.TotalDegree()
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	lt, err := poly.LeadingTerm("x")
+	require.NoError(t, err)
+	assert.Equal(t, "( * 1 ( + ( * 1 ( ^ a 1 ) ( ^ y 1 ) ) ( * 1 ( ^ b 1 ) ( ^ y 1 ) ) ) ( ^ x 2 ) )", lt.ToSExp().String())
+	lt, err = poly.LeadingTerm("y")
+	require.NoError(t, err)
+	assert.Equal(t, "( ^ y 3 )", lt.ToSExp().String())
+
+	c, err := poly.Coefficient("x", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 1 ( ^ a 1 ) ( ^ y 1 ) ) ( * 1 ( ^ b 1 ) ( ^ y 1 ) ) )", c.ToSExp().String())
+	c, err = poly.Coefficient("x", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( ^ y 3 ) 4 )", c.ToSExp().String())
+	c, err = poly.Coefficient("x", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "0", c.ToSExp().String())
+
+	_, err = poly.Coefficients("x")
+	assert.Error(t, err)
+
+	zero := polyFromString(t, "( + ( ^ x 1 ) ( * -1 ( ^ x 1 ) ) )")
+	n, err = zero.Degree("x")
+	require.NoError(t, err)
+	assert.Equal(t, -1, n)
+	n, err = zero.TotalDegree()
+	require.NoError(t, err)
+	assert.Equal(t, -1, n)
+
+	for _, raw := range []string{"( abs ( ^ x 1 ) )", "( ^ x -1 )", "( ^ x n )", "( ^ x 1/2 )"} {
+		p := polyFromString(t, raw, "n")
+		_, err = p.Degree("x")
+		assert.Error(t, err, raw)
+		_, err = p.TotalDegree()
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestCoefficients(t *testing.T) {
+	// characteristic polynomial ( l - 1 ) ( l + 2 )^2
+	poly := polyFromString(t, "( * 1 ( + ( ^ l 1 ) -1 ) ( ^ ( + ( ^ l 1 ) 2 ) 2 ) )")
+	cs, err := poly.Coefficients("l")
+	require.NoError(t, err)
+	assert.Equal(t, []Rational{Integer(-4), Integer(0), Integer(3), Integer(1)}, cs)
+
+	n, err := poly.Degree("l")
+	require.NoError(t, err)
+	assert.Equal(t, len(cs)-1, n)
+
+	zero := polyFromString(t, "( + ( ^ x 1 ) ( * -1 ( ^ x 1 ) ) )")
+	cs, err = zero.Coefficients("x")
+	require.NoError(t, err)
+	assert.Empty(t, cs)
+}
@@ -1,43 +1,165 @@
 package symdiff
-  
+
 import (
 	"fmt"
 )
 
 // Invariant: expression is checked as internally valid
 func Differentiate(v Symbol, exp PolyExp) (*PolyExp, error) {
-	if exp.s != nil {
-		sDiff, err := DifferentiateSum(v, *exp.s)
+	return differentiate(v, exp, nil)
+}
+
+// Differentiate recording the rule used at exp before the steps of its sub
+// expressions, a nil trace records nothing
+func differentiate(v Symbol, exp PolyExp, tr *Trace) (*PolyExp, error) {
+	i := tr.begin(exp.e.derivativeRule(v), exp)
+	d, err := exp.e.differentiate(v, tr)
+	if err != nil {
+		tr.truncate(i)
+		return nil, err
+	}
+	tr.end(i, *d)
+	return d, nil
+}
+
+func (sum *SumExp) Differentiate(v Symbol) (*PolyExp, error) {
+	return sum.differentiate(v, nil)
+}
+
+func (sum *SumExp) differentiate(v Symbol, tr *Trace) (*PolyExp, error) {
+	sDiff, err := differentiateSum(v, *sum, tr)
+	if err != nil {
+		return nil, err
+	}
+	return &PolyExp{
+		e: sDiff,
+	}, nil
+}
+
+func (a *ParamExp) Differentiate(v Symbol) (*PolyExp, error) {
+	return a.differentiate(v, nil)
+}
+
+func (a *ParamExp) differentiate(v Symbol, tr *Trace) (*PolyExp, error) {
+	if a.a == v {
+		return nil, fmt.Errorf("cannot take derivative d/d%s, %s is declared a parameter and parameters are constant", v, v)
+	}
+	return &PolyExp{
+		e: &ConstantExp{
+			c: Integer(0),
+		},
+	}, nil
+}
+
+func (c *ConstantExp) Differentiate(v Symbol) (*PolyExp, error) {
+	return c.differentiate(v, nil)
+}
+
+func (c *ConstantExp) differentiate(v Symbol, tr *Trace) (*PolyExp, error) {
+	return &PolyExp{
+		e: &ConstantExp{
+			c: Integer(0),
+		},
+	}, nil
+}
+
+func (p *ProductExp) Differentiate(v Symbol) (*PolyExp, error) {
+	return p.differentiate(v, nil)
+}
+
+func (p *ProductExp) differentiate(v Symbol, tr *Trace) (*PolyExp, error) {
+	if len(p.fs) > 0 {
+		factorsDiff, err := differentiateFactors(v, *p, tr)
 		if err != nil {
 			return nil, err
 		}
-		return &PolyExp {
-			s: sDiff,
+		return &PolyExp{
+			e: factorsDiff,
 		}, nil
 	}
-	if exp.c != nil {
-		return &PolyExp {
-			c: &ConstantExp {
-				c: 0,
-			},
-		}, nil
+	productDiff, err := differentiateProduct(v, *p, tr)
+	if err != nil {
+		return nil, err
 	}
-	if exp.p != nil {
-		productDiff, err := DifferentiateProduct(v, *exp.p)
-		if err != nil {
+	return &PolyExp{
+		e: productDiff,
+	}, nil
+}
+
+func (pow *PowerExp) Differentiate(v Symbol) (*PolyExp, error) {
+	return pow.differentiate(v, nil)
+}
+
+func (pow *PowerExp) differentiate(v Symbol, tr *Trace) (*PolyExp, error) {
+	// Powers of parameters are constant, d/da is rejected as for the parameter
+	if pow.isCoefficient() {
+		if _, err := pow.b.param().differentiate(v, nil); err != nil {
 			return nil, err
 		}
-		return &PolyExp {
-			p: productDiff,
-		}, nil
+		return &PolyExp{e: &ConstantExp{c: Integer(0)}}, nil
+	}
+	powerDiff, err := differentiatePower(v, *pow, tr)
+	if err != nil {
+		return nil, err
+	}
+	return &PolyExp{
+		e: powerDiff,
+	}, nil
+}
+
+func (a *AbsExp) Differentiate(v Symbol) (*PolyExp, error) {
+	return a.differentiate(v, nil)
+}
+
+func (a *AbsExp) differentiate(v Symbol, tr *Trace) (*PolyExp, error) {
+	absDiff, err := differentiateAbs(v, *a, tr)
+	if err != nil {
+		return nil, err
+	}
+	return &PolyExp{
+		e: absDiff,
+	}, nil
+}
 
+func (sgn *SignExp) Differentiate(v Symbol) (*PolyExp, error) {
+	return sgn.differentiate(v, nil)
+}
+
+func (sgn *SignExp) differentiate(v Symbol, tr *Trace) (*PolyExp, error) {
+	signDiff, err := DifferentiateSign(v, *sgn)
+	if err != nil {
+		return nil, err
 	}
-	mDiff, err := DifferentiateMonomial(v, *exp.m)
+	return &PolyExp{
+		e: signDiff,
+	}, nil
+}
+
+func (pw *PiecewiseExp) Differentiate(v Symbol) (*PolyExp, error) {
+	return pw.differentiate(v, nil)
+}
+
+func (pw *PiecewiseExp) differentiate(v Symbol, tr *Trace) (*PolyExp, error) {
+	piecewiseDiff, err := differentiatePiecewise(v, *pw, tr)
 	if err != nil {
 		return nil, err
 	}
-	return &PolyExp {
-		p: mDiff,
+	return &PolyExp{
+		e: piecewiseDiff,
+	}, nil
+}
+
+func (m *MonomialExp) Differentiate(v Symbol) (*PolyExp, error) {
+	return m.differentiate(v, nil)
+}
+
+func (m *MonomialExp) differentiate(v Symbol, tr *Trace) (*PolyExp, error) {
+	mDiff, err := DifferentiateMonomial(v, *m)
+	if err != nil {
+		return nil, err
+	}
+	return &PolyExp{
+		e: mDiff,
 	}, nil
 }
 
@@ -45,47 +167,200 @@ func DifferentiateMonomial(v Symbol, mon MonomialExp) (*ProductExp, error) {
 	if v != mon.x {
 		return nil, fmt.Errorf("Cannot take deriviative d/d%s of polynomial function of different bound variable %s", v, mon.x)
 	}
+	if mon.e != nil {
+		// d/dx x^n = n x^(n-1), exponent is constant in x by construction
+		n := *mon.e
+		return &ProductExp{
+			l: &n,
+			r: &PolyExp{
+				e: &MonomialExp{
+					x: mon.x,
+					e: &PolyExp{
+						e: &SumExp{
+							ps: []PolyExp{n, {e: &ConstantExp{c: Integer(-1)}}},
+						},
+					},
+				},
+			},
+		}, nil
+	}
 	var inner MonomialExp
-	var multiplicand int
-	if mon.n == 0 { // we could use constant as well but we'll let simplification normalize to keep differentiation simple
+	var multiplicand Rational
+	if mon.n.IsZero() { // we could use constant as well but we'll let simplification normalize to keep differentiation simple
 		inner = MonomialExp{
 			x: mon.x,
-			n: 0,
+			n: Integer(0),
 		}
-		multiplicand = 0
+		multiplicand = Integer(0)
 	} else {
 		inner = MonomialExp{
 			x: mon.x,
-			n: mon.n - 1,
+			n: mon.n.Sub(Integer(1)),
 		}
 		multiplicand = mon.n
 	}
-	
+
 	return &ProductExp{
-		l: &ConstantExp{
-			c: multiplicand,
+		l: &PolyExp{
+			e: &ConstantExp{
+				c: multiplicand,
+			},
 		},
 		r: &PolyExp{
-			m: &inner,
+			e: &inner,
 		},
 	}, nil
 }
 
 func DifferentiateProduct(v Symbol, prod ProductExp) (*ProductExp, error) {
-	diff, err := Differentiate(v, *prod.r)
+	return differentiateProduct(v, prod, nil)
+}
+
+func differentiateProduct(v Symbol, prod ProductExp, tr *Trace) (*ProductExp, error) {
+	if prod.l.mentions(v) {
+		return nil, fmt.Errorf("Cannot take derivative d/d%s of product with coefficient %s depending on %s", v, prod.l.ToSExp().String(), v)
+	}
+	diff, err := differentiate(v, *prod.r, tr)
 	if err != nil {
 		return nil, err
 	}
-	return &ProductExp {
+	return &ProductExp{
 		l: prod.l,
 		r: diff,
 	}, nil
 }
 
+// Product rule over all factors, the coefficient is constant in v
+// d/dx ( * a f g ) = ( + ( * a f' g ) ( * a f g' ) )
+func DifferentiateFactors(v Symbol, prod ProductExp) (*SumExp, error) {
+	return differentiateFactors(v, prod, nil)
+}
+
+func differentiateFactors(v Symbol, prod ProductExp, tr *Trace) (*SumExp, error) {
+	if prod.l.mentions(v) {
+		return nil, fmt.Errorf("Cannot take derivative d/d%s of product with coefficient %s depending on %s", v, prod.l.ToSExp().String(), v)
+	}
+	_, factors := prod.Term()
+	ret := SumExp{ps: make([]PolyExp, len(factors))}
+	for i := range factors {
+		diff, err := differentiate(v, factors[i], tr)
+		if err != nil {
+			return nil, err
+		}
+		term := make([]PolyExp, len(factors))
+		copy(term, factors)
+		term[i] = *diff
+		ret.ps[i] = PolyExp{
+			e: &ProductExp{
+				l:  prod.l,
+				r:  &term[0],
+				fs: term[1:],
+			},
+		}
+	}
+	return &ret, nil
+}
+
+// Chain rule for powers of expressions
+// d/dx ( ^ f q ) = ( * q ( ^ f q-1 ) f' )
+func DifferentiatePower(v Symbol, pow PowerExp) (*ProductExp, error) {
+	return differentiatePower(v, pow, nil)
+}
+
+func differentiatePower(v Symbol, pow PowerExp, tr *Trace) (*ProductExp, error) {
+	diff, err := differentiate(v, *pow.b, tr)
+	if err != nil {
+		return nil, err
+	}
+	return &ProductExp{
+		l: &PolyExp{
+			e: &ConstantExp{
+				c: pow.n,
+			},
+		},
+		r: &PolyExp{
+			e: &PowerExp{
+				b: pow.b,
+				n: pow.n.Sub(Integer(1)),
+			},
+		},
+		fs: []PolyExp{*diff},
+	}, nil
+}
+
+// d/dx |f| = -f' where f < 0 and f' where f > 0
+// The derivative is undefined where f = 0 and neither piece applies
+func DifferentiateAbs(v Symbol, abs AbsExp) (*PiecewiseExp, error) {
+	return differentiateAbs(v, abs, nil)
+}
+
+func differentiateAbs(v Symbol, abs AbsExp, tr *Trace) (*PiecewiseExp, error) {
+	diff, err := differentiate(v, *abs.e, tr)
+	if err != nil {
+		return nil, err
+	}
+	return &PiecewiseExp{
+		cs: signConditions(*abs.e),
+		es: []PolyExp{
+			{
+				e: &ProductExp{
+					l: &PolyExp{e: &ConstantExp{c: Integer(-1)}},
+					r: diff,
+				},
+			},
+			*diff,
+		},
+	}, nil
+}
+
+// d/dx sign(f) = 0 where f < 0 or f > 0
+// The derivative is undefined where f = 0 and neither piece applies
+func DifferentiateSign(v Symbol, sgn SignExp) (*PiecewiseExp, error) {
+	return &PiecewiseExp{
+		cs: signConditions(*sgn.e),
+		es: []PolyExp{Zero(), Zero()},
+	}, nil
+}
+
+// ( < f 0 ) and ( > f 0 )
+func signConditions(f PolyExp) []Condition {
+	return []Condition{
+		{op: LessKeyWord, l: &f, r: &PolyExp{e: &ConstantExp{c: Integer(0)}}},
+		{op: GreaterKeyWord, l: &f, r: &PolyExp{e: &ConstantExp{c: Integer(0)}}},
+	}
+}
+
+// Differentiate each piece keeping its condition
+// On the boundary between two pieces this gives the derivative of the
+// selected piece, which is one sided.  The derivative of the piecewise
+// function itself is undefined there unless the pieces' derivatives agree.
+func DifferentiatePiecewise(v Symbol, pw PiecewiseExp) (*PiecewiseExp, error) {
+	return differentiatePiecewise(v, pw, nil)
+}
+
+func differentiatePiecewise(v Symbol, pw PiecewiseExp, tr *Trace) (*PiecewiseExp, error) {
+	ret := PiecewiseExp{
+		cs: pw.cs,
+		es: make([]PolyExp, len(pw.es)),
+	}
+	for i := range pw.es {
+		diff, err := differentiate(v, pw.es[i], tr)
+		if err != nil {
+			return nil, err
+		}
+		ret.es[i] = *diff
+	}
+	return &ret, nil
+}
+
 func DifferentiateSum(v Symbol, sum SumExp) (*SumExp, error) {
+	return differentiateSum(v, sum, nil)
+}
+
+func differentiateSum(v Symbol, sum SumExp, tr *Trace) (*SumExp, error) {
 	ret := SumExp{ps: make([]PolyExp, len(sum.ps))}
 	for i := range sum.ps {
-		diff, err := Differentiate(v, sum.ps[i])
+		diff, err := differentiate(v, sum.ps[i], tr)
 		if err != nil {
 			return nil, err
 		}
@@ -93,4 +368,3 @@ func DifferentiateSum(v Symbol, sum SumExp) (*SumExp, error) {
 	}
 	return &ret, nil
 }
-
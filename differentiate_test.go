@@ -44,10 +44,113 @@ func TestDiffSumm(t *testing.T) {
 	assert.Equal(t, expected, derivative.ToSExp().String())
 }
 
-func polyFromString(t *testing.T, raw string) PolyExp {
+func polyFromString(t *testing.T, raw string, params ...Symbol) PolyExp {
 	var sexp SExp
 	require.NoError(t, sexp.Parse(raw))
 	var poly PolyExp
-	require.NoError(t, poly.Parse(sexp))
+	require.NoError(t, poly.ParseParams(sexp, params))
 	return poly
 }
+
+func TestDiffSymbolicExponent(t *testing.T) {
+	poly := polyFromString(t, "(^ x n)", "n")
+
+	derivative, err := Differentiate("x", poly)
+	assert.NoError(t, err)
+	expected := "( * n ( ^ x ( + n -1 ) ) )"
+	assert.Equal(t, expected, derivative.ToSExp().String())
+
+	poly = polyFromString(t, "(* 3 (^ x (+ n 1)))", "n")
+	derivative, err = Differentiate("x", poly)
+	assert.NoError(t, err)
+	expected = "( * 3 ( * ( + n 1 ) ( ^ x ( + ( + n 1 ) -1 ) ) ) )"
+	assert.Equal(t, expected, derivative.ToSExp().String())
+
+	s, err := Simplify(*derivative)
+	assert.NoError(t, err)
+	assert.Equal(t, "( * ( + ( * 3 n ) 3 ) ( ^ x n ) )", s.ToSExp().String())
+
+	// parameters are constant
+	derivative, err = Differentiate("x", polyFromString(t, "n", "n"))
+	assert.NoError(t, err)
+	assert.Equal(t, Zero(), *derivative)
+}
+
+func TestDiffSymbolicCoefficients(t *testing.T) {
+	// a x^2 + b x + c
+	poly := polyFromString(t, "( + ( * a ( ^ x 2 ) ) ( * b ( ^ x 1 ) ) c )", "a", "b", "c")
+
+	derivative, err := Differentiate("x", poly)
+	assert.NoError(t, err)
+	s, err := Simplify(*derivative)
+	assert.NoError(t, err)
+	assert.Equal(t, "( + ( * ( * 2 a ) ( ^ x 1 ) ) b )", s.ToSExp().String())
+
+	// powers of parameters are coefficients
+	for raw, expected := range map[string]string{
+		"( * ( ^ a 2 ) ( ^ x 2 ) )":    "( * ( * 2 ( ^ a 2 ) ) ( ^ x 1 ) )",
+		"( + ( ^ a 2 ) ( ^ x 2 ) )":    "( * 2 ( ^ x 1 ) )",
+		"( * ( ^ a -1/2 ) ( ^ x 1 ) )": "( ^ a -1/2 )",
+	} {
+		derivative, err = Differentiate("x", polyFromString(t, raw, "a"))
+		require.NoError(t, err, raw)
+		s, err = Simplify(*derivative)
+		require.NoError(t, err, raw)
+		assert.Equal(t, expected, s.ToSExp().String(), raw)
+	}
+	_, err = Differentiate("a", polyFromString(t, "( ^ a 2 )", "a"))
+	assert.Error(t, err)
+
+	// coefficients must be constant in the variable
+	_, err = Differentiate("x", polyFromString(t, "( * x ( ^ x 2 ) )", "x"))
+	assert.Error(t, err)
+	_, err = Differentiate("x", polyFromString(t, "x", "x"))
+	assert.Error(t, err)
+}
+
+func TestDiffUndeclaredSymbols(t *testing.T) {
+	// undeclared symbols are variables
+	poly := polyFromString(t, "( + ( * 3 x ) 1 )")
+	assert.Equal(t, "( + ( * 3 ( ^ x 1 ) ) 1 )", poly.ToSExp().String())
+	derivative, err := Differentiate("x", poly)
+	require.NoError(t, err)
+	s, err := Simplify(*derivative)
+	require.NoError(t, err)
+	assert.Equal(t, "3", s.ToSExp().String())
+
+	// so they may not appear in exponents
+	var sexp SExp
+	require.NoError(t, sexp.Parse("( ^ x n )"))
+	var bad PolyExp
+	assert.Error(t, bad.Parse(sexp))
+	assert.NoError(t, bad.ParseParams(sexp, []Symbol{"n"}))
+}
+
+func TestDiffRationalExponents(t *testing.T) {
+	derivative, err := Differentiate("x", polyFromString(t, "( * 3 ( ^ x -3/2 ) )"))
+	assert.NoError(t, err)
+	assert.Equal(t, "( * 3 ( * -3/2 ( ^ x -5/2 ) ) )", derivative.ToSExp().String())
+	s, err := Simplify(*derivative)
+	assert.NoError(t, err)
+	assert.Equal(t, "( * -9/2 ( ^ x -5/2 ) )", s.ToSExp().String())
+
+	// chain rule through square roots
+	derivative, err = Differentiate("x", polyFromString(t, "( sqrt ( + ( ^ x 2 ) 1 ) )"))
+	assert.NoError(t, err)
+	s, err = Simplify(*derivative)
+	assert.NoError(t, err)
+	assert.Equal(t, "( * 1/2 ( ^ ( + ( ^ x 2 ) 1 ) -1/2 ) ( * 2 ( ^ x 1 ) ) )", s.ToSExp().String())
+
+	derivative, err = Differentiate("x", polyFromString(t, "( * 2 ( sqrt ( ^ x 1 ) ) )"))
+	assert.NoError(t, err)
+	s, err = Simplify(*derivative)
+	assert.NoError(t, err)
+	assert.Equal(t, "( ^ x -1/2 )", s.ToSExp().String())
+
+	// product rule over several factors, factors are not multiplied out
+	derivative, err = Differentiate("x", polyFromString(t, "( * 2 ( ^ x 1 ) ( sqrt ( ^ x 1 ) ) )"))
+	assert.NoError(t, err)
+	s, err = Simplify(*derivative)
+	assert.NoError(t, err)
+	assert.Equal(t, "( + ( * 2 ( ^ x 1 ) ( * 1/2 ( ^ x -1/2 ) ) ) ( * 2 ( ^ x 1/2 ) ) )", s.ToSExp().String())
+}
@@ -0,0 +1,68 @@
+package symdiff
+
+import (
+	"fmt"
+)
+
+// Quotient q and remainder r of univariate polynomial division in v,
+// a = q b + r with r of lower degree than b
+// Both polynomials must be in v only with rational coefficients, after
+// expanding
+func DivMod(a, b PolyExp, v Symbol) (*PolyExp, *PolyExp, error) {
+	pa, err := toUnivariateIn(a, v)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s, invalid dividend", err)
+	}
+	pb, err := toUnivariateIn(b, v)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s, invalid divisor", err)
+	}
+	if pb.degree() < 0 {
+		return nil, nil, fmt.Errorf("division of %s by zero", a.ToSExp().String())
+	}
+	q, r, err := pa.divmod(pb)
+	if err != nil {
+		return nil, nil, err
+	}
+	quo, rem := q.toPolyExp(v), r.toPolyExp(v)
+	return &quo, &rem, nil
+}
+
+// Greatest common divisor of polynomials in the same variable with rational
+// coefficients, normalized to coprime integer coefficients with a positive
+// leading coefficient.  The GCD of constants is 1 unless both are 0.
+func GCD(a, b PolyExp) (*PolyExp, error) {
+	x, pa, err := toUnivariate(a)
+	if err != nil {
+		return nil, err
+	}
+	y, pb, err := toUnivariate(b)
+	if err != nil {
+		return nil, err
+	}
+	if x != "" && y != "" && x != y {
+		return nil, fmt.Errorf("polynomials in different variables %s and %s", x, y)
+	}
+	if x == "" {
+		x = y
+	}
+	g, err := pa.gcd(pb)
+	if err != nil {
+		return nil, err
+	}
+	_, g = g.primitive()
+	ret := g.toPolyExp(x)
+	return &ret, nil
+}
+
+// Polynomial in v with rational coefficients, see toUnivariate
+func toUnivariateIn(exp PolyExp, v Symbol) (upoly, error) {
+	x, p, err := toUnivariate(exp)
+	if err != nil {
+		return nil, err
+	}
+	if x != "" && x != v {
+		return nil, fmt.Errorf("polynomial in %s instead of %s", x, v)
+	}
+	return p, nil
+}
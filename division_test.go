@@ -0,0 +1,86 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestDivMod(t *testing.T) {
+	cases := []struct {
+		a, b, q, r string
+	}{
+		{"( + ( ^ x 3 ) -1 )", "( + ( ^ x 1 ) -1 )", "( + ( ^ x 1 ) ( ^ x 2 ) 1 )", "0"},
+		{"( + ( ^ x 3 ) ( * 2 ( ^ x 1 ) ) 1 )", "( + ( ^ x 2 ) 1 )", "( ^ x 1 )", "( + ( ^ x 1 ) 1 )"},
+		{"( + ( ^ x 2 ) 1 )", "( + ( * 2 ( ^ x 1 ) ) 1 )", "( + ( * 1/2 ( ^ x 1 ) ) -1/4 )", "5/4"},
+		{"( + ( ^ x 1 ) 1 )", "( ^ x 2 )", "0", "( + ( ^ x 1 ) 1 )"},
+		{"( * 1 ( + ( ^ x 1 ) 2 ) ( + ( ^ x 1 ) 3 ) )", "( + ( ^ x 1 ) 3 )", "( + ( ^ x 1 ) 2 )", "0"},
+		{"( + ( ^ x 2 ) 1 )", "2", "( + ( * 1/2 ( ^ x 2 ) ) 1/2 )", "0"},
+		{"5", "( ^ x 1 )", "0", "5"},
+		// coefficients beyond machine integers
+		{"( + ( ^ x 3 ) ( * 123456789012 ( ^ x 1 ) ) 100000000000000000000 )", "( + ( * 987654321 ( ^ x 2 ) ) 1 )", "( * 1/987654321 ( ^ x 1 ) )", "( + ( * 121932631124487120851/987654321 ( ^ x 1 ) ) 100000000000000000000 )"},
+	}
+	for _, c := range cases {
+		a := polyFromString(t, c.a)
+		b := polyFromString(t, c.b)
+		q, r, err := DivMod(a, b, "x")
+		require.NoError(t, err, c.a)
+		assert.Equal(t, c.q, q.ToSExp().String(), c.a)
+		assert.Equal(t, c.r, r.ToSExp().String(), c.a)
+
+		// a = q b + r
+		qb, err := Mul(*q, b)
+		require.NoError(t, err)
+		sum, err := Sum(qb, *r)
+		require.NoError(t, err)
+		eq, err := Equivalent(a, sum)
+		require.NoError(t, err)
+		assert.True(t, eq.Equivalent, c.a)
+	}
+}
+
+func TestDivModInvalid(t *testing.T) {
+	x := polyFromString(t, "( ^ x 1 )")
+	for _, c := range [][2]string{
+		{"( ^ x 2 )", "0"},
+		{"( ^ x 2 )", "( + ( ^ x 1 ) ( * -1 ( ^ x 1 ) ) )"},
+		{"( ^ y 2 )", "( ^ x 1 )"},
+		{"( ^ x 2 )", "( ^ y 1 )"},
+		{"( + ( ^ x 2 ) a )", "( ^ x 1 )"},
+		{"( ^ x 1/2 )", "( ^ x 1 )"},
+	} {
+		_, _, err := DivMod(polyFromString(t, c[0]), polyFromString(t, c[1]), "x")
+		assert.Error(t, err, c)
+	}
+	_, _, err := DivMod(x, x, "y")
+	assert.Error(t, err)
+}
+
+func TestGCD(t *testing.T) {
+	cases := []struct {
+		a, b, gcd string
+	}{
+		{"( + ( ^ x 2 ) -1 )", "( + ( ^ x 2 ) ( * 2 ( ^ x 1 ) ) 1 )", "( + ( ^ x 1 ) 1 )"},
+		{"( + ( * 4 ( ^ x 2 ) ) -1 )", "( + ( * 6 ( ^ x 1 ) ) 3 )", "( + ( * 2 ( ^ x 1 ) ) 1 )"},
+		{"( + ( ^ x 2 ) 1 )", "( + ( ^ x 1 ) 1 )", "1"},
+		{"( * 3 ( ^ x 3 ) )", "( * 6 ( ^ x 2 ) )", "( ^ x 2 )"},
+		{"( + ( ^ x 2 ) -1 )", "0", "( + ( ^ x 2 ) -1 )"},
+		{"0", "( + ( * -2 ( ^ x 1 ) ) 2 )", "( + ( ^ x 1 ) -1 )"},
+		{"4", "6", "1"},
+		{"0", "0", "0"},
+		// remainders with coefficients beyond machine integers
+		{"( * 1 ( + ( ^ x 1 ) 100000000000000000000 ) ( + ( * 999999937 ( ^ x 2 ) ) 12345678901 ) )", "( * 1 ( + ( ^ x 1 ) 100000000000000000000 ) ( + ( * 3 ( ^ x 3 ) ) -987654321987 ) )", "( + ( ^ x 1 ) 100000000000000000000 )"},
+	}
+	for _, c := range cases {
+		g, err := GCD(polyFromString(t, c.a), polyFromString(t, c.b))
+		require.NoError(t, err, c.a)
+		assert.Equal(t, c.gcd, g.ToSExp().String(), c.a)
+	}
+
+	_, err := GCD(polyFromString(t, "( ^ x 1 )"), polyFromString(t, "( ^ y 1 )"))
+	assert.Error(t, err)
+	_, err = GCD(polyFromString(t, "( ^ x 1 )"), polyFromString(t, "( abs ( ^ x 1 ) )"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package symdiff
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+/*
+   Equivalence of expressions as functions of their symbols
+
+   Expressions with equal canonical forms are equivalent.  A symbol that is
+   a variable on either side is a variable on both, so a parameter x and
+   ( ^ x 1 ) canonicalize alike.  Polynomials, i.e.
+   expressions built from constants, parameters, monomials with numeric
+   exponents, sums and products, with different canonical forms differ, and
+   the point where they do is found by probing.  Anything richer (powers of
+   sums, abs, sign, piecewise expressions, symbolic exponents) is probed at
+   pseudo random points, skipping points where either side has no value.
+   Probing is seeded so results are reproducible.
+*/
+
+// Number of points probed when an equivalence can not be decided exactly
+const EquivalenceProbes = 200
+
+// Relative tolerance of numeric comparisons when probing
+const EquivalenceTolerance = 1e-9
+
+// Result of Equivalent
+type Equivalence struct {
+	Equivalent bool
+	// Decided via canonical forms rather than probing
+	Exact bool
+	// Point where the expressions differ with their values there, nil when
+	// equivalent or when no point was found
+	Counterexample map[Symbol]float64
+	A, B           float64
+}
+
+func (eq *Equivalence) String() string {
+	how := "numerically"
+	if eq.Exact {
+		how = "exactly"
+	}
+	if eq.Equivalent {
+		return fmt.Sprintf("equivalent (%s)", how)
+	}
+	if eq.Counterexample == nil {
+		return fmt.Sprintf("not equivalent (%s)", how)
+	}
+	names := make([]string, 0, len(eq.Counterexample))
+	for s := range eq.Counterexample {
+		names = append(names, string(s))
+	}
+	sort.Strings(names)
+	bindings := make([]string, len(names))
+	for i, s := range names {
+		bindings[i] = fmt.Sprintf("%s=%v", s, eq.Counterexample[Symbol(s)])
+	}
+	return fmt.Sprintf("not equivalent (%s), at %s: %v != %v", how, strings.Join(bindings, " "), eq.A, eq.B)
+}
+
+// Decide whether a and b denote the same function, see above
+func Equivalent(a, b PolyExp) (*Equivalence, error) {
+	a, b = unifySymbols(a, b), unifySymbols(b, a)
+	ca, err := Canonicalize(a)
+	if err != nil {
+		return nil, fmt.Errorf("%s, failed to canonicalize %s", err, a.ToSExp().String())
+	}
+	cb, err := Canonicalize(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s, failed to canonicalize %s", err, b.ToSExp().String())
+	}
+	if ca.Equal(cb) {
+		return &Equivalence{Equivalent: true, Exact: true}, nil
+	}
+	exact := isPolynomial(*ca) && isPolynomial(*cb)
+	eq, err := probe(a, b)
+	if err != nil {
+		if exact {
+			// canonical forms decide, there just is no point to show
+			return &Equivalence{Exact: true}, nil
+		}
+		return nil, err
+	}
+	// polynomials with different canonical forms agreeing at every probe
+	// means canonicalization missed an identity, trust the probes
+	eq.Exact = exact && !eq.Equivalent
+	return eq, nil
+}
+
+// a with its parameters that are variables in b made variables, a is kept
+// when they can't be, i.e. when they are exponents
+func unifySymbols(a, b PolyExp) PolyExp {
+	with := make(map[Symbol]PolyExp)
+	Inspect(b, func(e PolyExp) bool {
+		if e.IsMon() {
+			x, err := Var(e.mon().x)
+			if err == nil && a.mentions(e.mon().x) {
+				with[e.mon().x] = x
+			}
+		}
+		return true
+	})
+	if len(with) == 0 {
+		return a
+	}
+	ret, err := substituteExp(a, with)
+	if err != nil {
+		return a
+	}
+	return ret
+}
+
+// Compare a and b at pseudo random points
+func probe(a, b PolyExp) (*Equivalence, error) {
+	syms := symbolsOf(a, b)
+	rng := rand.New(rand.NewSource(1))
+	defined := 0
+	for i := 0; i < EquivalenceProbes; i++ {
+		env := make(map[Symbol]float64, len(syms))
+		for _, s := range syms {
+			env[s] = probeValue(rng)
+		}
+		va, erra := Evaluate(a, env)
+		vb, errb := Evaluate(b, env)
+		if erra != nil || errb != nil {
+			continue
+		}
+		defined++
+		if !approxEqual(va, vb) {
+			return &Equivalence{Counterexample: env, A: va, B: vb}, nil
+		}
+	}
+	if defined == 0 {
+		return nil, fmt.Errorf("no point found in %d probes where both %s and %s have a value", EquivalenceProbes, a.ToSExp().String(), b.ToSExp().String())
+	}
+	return &Equivalence{Equivalent: true}, nil
+}
+
+// Small integers, which hit piecewise boundaries and zeros, or reals in
+// [-10, 10]
+func probeValue(rng *rand.Rand) float64 {
+	if rng.Intn(4) == 0 {
+		return float64(rng.Intn(7) - 3)
+	}
+	return 20*rng.Float64() - 10
+}
+
+func approxEqual(a, b float64) bool {
+	scale := math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+	return math.Abs(a-b) <= EquivalenceTolerance*scale
+}
+
+// Sorted variable and parameter symbols of the expressions
+func symbolsOf(exps ...PolyExp) []Symbol {
+	seen := make(map[Symbol]bool)
+	for _, exp := range exps {
+		Inspect(exp, func(e PolyExp) bool {
+			if e.IsMon() {
+				seen[e.mon().x] = true
+			} else if e.IsParam() {
+				seen[e.param().a] = true
+			}
+			return true
+		})
+	}
+	ret := make([]Symbol, 0, len(seen))
+	for s := range seen {
+		ret = append(ret, s)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// Built from constants, parameters, monomials with numeric exponents, sums
+// and products only
+func isPolynomial(exp PolyExp) bool {
+	ret := true
+	Inspect(exp, func(e PolyExp) bool {
+		if e.isCompound() || (e.IsMon() && e.mon().e != nil) {
+			ret = false
+		}
+		return ret
+	})
+	return ret
+}
@@ -0,0 +1,84 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestEquivalentExact(t *testing.T) {
+	eq, err := Equivalent(
+		polyFromString(t, "( * 1 ( + ( ^ x 1 ) 1 ) ( + ( ^ x 1 ) -1 ) )"),
+		polyFromString(t, "( + ( ^ x 2 ) -1 )"),
+	)
+	require.NoError(t, err)
+	assert.True(t, eq.Equivalent)
+	assert.True(t, eq.Exact)
+	assert.Nil(t, eq.Counterexample)
+
+	a := polyFromString(t, "( + ( ^ x 2 ) ( * a ( ^ y 1 ) ) )")
+	b := polyFromString(t, "( + ( ^ x 2 ) ( * a ( ^ x 1 ) ) )")
+	eq, err = Equivalent(a, b)
+	require.NoError(t, err)
+	assert.False(t, eq.Equivalent)
+	assert.True(t, eq.Exact)
+	require.NotNil(t, eq.Counterexample)
+	va, err := Evaluate(a, eq.Counterexample)
+	require.NoError(t, err)
+	vb, err := Evaluate(b, eq.Counterexample)
+	require.NoError(t, err)
+	assert.Equal(t, va, eq.A)
+	assert.Equal(t, vb, eq.B)
+	assert.NotEqual(t, eq.A, eq.B)
+	assert.Contains(t, eq.String(), "not equivalent (exactly), at a=")
+
+	// identical functions are decided exactly whatever the roles of symbols
+	for _, c := range [][2]PolyExp{
+		{polyFromString(t, "x"), polyFromString(t, "( ^ x 1 )")},
+		{polyFromString(t, "x", "x"), polyFromString(t, "( ^ x 1 )")},
+		{polyFromString(t, "( abs ( ^ x 1 ) )"), polyFromString(t, "( abs x )", "x")},
+	} {
+		eq, err = Equivalent(c[0], c[1])
+		require.NoError(t, err)
+		assert.Equal(t, "equivalent (exactly)", eq.String(), "%s %s", c[0].ToSExp().String(), c[1].ToSExp().String())
+	}
+}
+
+func TestEquivalentNumeric(t *testing.T) {
+	cases := []struct {
+		a, b       string
+		equivalent bool
+	}{
+		{"( sqrt ( ^ x 2 ) )", "( abs ( ^ x 1 ) )", true},
+		{"( * 1 ( abs ( ^ x 1 ) ) ( sign ( ^ x 1 ) ) )", "( ^ x 1 )", true},
+		{"( piecewise ( ( < ( ^ x 1 ) 0 ) 0 ) ( else ( ^ x 1 ) ) )", "( * 1/2 ( + ( ^ x 1 ) ( abs ( ^ x 1 ) ) ) )", true},
+		{"( sqrt ( ^ x 2 ) )", "( ^ x 1 )", false},
+		{"( ^ ( + ( ^ x 1 ) 1 ) 3 )", "( + ( ^ x 3 ) ( * 3 ( ^ x 2 ) ) ( * 3 ( ^ x 1 ) ) 1 )", true},
+	}
+	for _, c := range cases {
+		eq, err := Equivalent(polyFromString(t, c.a), polyFromString(t, c.b))
+		require.NoError(t, err, c.a)
+		assert.Equal(t, c.equivalent, eq.Equivalent, "%s %s", c.a, c.b)
+		assert.Equal(t, c.equivalent, eq.Counterexample == nil, "%s %s", c.a, c.b)
+	}
+
+	// nowhere both defined
+	_, err := Equivalent(
+		polyFromString(t, "( ^ ( + ( * -1 ( abs ( ^ x 1 ) ) ) -1 ) 1/2 )"),
+		polyFromString(t, "( abs ( ^ x 1 ) )"),
+	)
+	assert.Error(t, err)
+}
+
+func TestEquivalentDerivative(t *testing.T) {
+	poly := polyFromString(t, "( * 1 ( ^ x 2 ) ( sqrt ( + ( ^ x 2 ) 1 ) ) )")
+	d, err := Differentiate("x", poly)
+	require.NoError(t, err)
+	// hand derived 2x sqrt(x^2+1) + x^3 / sqrt(x^2+1)
+	byHand := polyFromString(t, "( + ( * 2 ( ^ x 1 ) ( sqrt ( + ( ^ x 2 ) 1 ) ) ) ( * 1 ( ^ x 3 ) ( ^ ( + ( ^ x 2 ) 1 ) -1/2 ) ) )")
+	eq, err := Equivalent(*d, byHand)
+	require.NoError(t, err)
+	assert.True(t, eq.Equivalent, eq.String())
+}
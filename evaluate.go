@@ -0,0 +1,159 @@
+package symdiff
+
+import (
+	"fmt"
+	"math"
+)
+
+// Evaluate expression at the point given by env, binding every variable and
+// parameter symbol to a value
+// Powers without a real value, i.e. negative bases with even root exponents,
+// division by zero and points where no piece of a piecewise expression
+// applies are errors
+func Evaluate(exp PolyExp, env map[Symbol]float64) (float64, error) {
+	return exp.e.Evaluate(env)
+}
+
+func (c *ConstantExp) Evaluate(env map[Symbol]float64) (float64, error) {
+	return c.c.Float64(), nil
+}
+
+func (a *ParamExp) Evaluate(env map[Symbol]float64) (float64, error) {
+	return lookup(a.a, env)
+}
+
+func (m *MonomialExp) Evaluate(env map[Symbol]float64) (float64, error) {
+	x, err := lookup(m.x, env)
+	if err != nil {
+		return 0, err
+	}
+	if m.e == nil {
+		return rationalPower(x, m.n)
+	}
+	n, err := Evaluate(*m.e, env)
+	if err != nil {
+		return 0, err
+	}
+	return realPower(x, n)
+}
+
+func (pow *PowerExp) Evaluate(env map[Symbol]float64) (float64, error) {
+	b, err := Evaluate(*pow.b, env)
+	if err != nil {
+		return 0, err
+	}
+	return rationalPower(b, pow.n)
+}
+
+func (a *AbsExp) Evaluate(env map[Symbol]float64) (float64, error) {
+	v, err := Evaluate(*a.e, env)
+	if err != nil {
+		return 0, err
+	}
+	return math.Abs(v), nil
+}
+
+func (sgn *SignExp) Evaluate(env map[Symbol]float64) (float64, error) {
+	v, err := Evaluate(*sgn.e, env)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case v < 0:
+		return -1, nil
+	case v > 0:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (pw *PiecewiseExp) Evaluate(env map[Symbol]float64) (float64, error) {
+	return evaluatePiecewise(*pw, env)
+}
+
+func (p *ProductExp) Evaluate(env map[Symbol]float64) (float64, error) {
+	l, factors := p.Term()
+	ret, err := Evaluate(l, env)
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range factors {
+		v, err := Evaluate(f, env)
+		if err != nil {
+			return 0, err
+		}
+		ret *= v
+	}
+	return ret, nil
+}
+
+func (sum *SumExp) Evaluate(env map[Symbol]float64) (float64, error) {
+	var ret float64
+	for _, p := range sum.ps {
+		v, err := Evaluate(p, env)
+		if err != nil {
+			return 0, err
+		}
+		ret += v
+	}
+	return ret, nil
+}
+
+// Value of the first piece whose condition holds
+func evaluatePiecewise(pw PiecewiseExp, env map[Symbol]float64) (float64, error) {
+	for i, c := range pw.cs {
+		if !c.IsElse() {
+			l, err := Evaluate(*c.l, env)
+			if err != nil {
+				return 0, err
+			}
+			r, err := Evaluate(*c.r, env)
+			if err != nil {
+				return 0, err
+			}
+			if !c.holds(l, r) {
+				continue
+			}
+		}
+		return Evaluate(pw.es[i], env)
+	}
+	return 0, fmt.Errorf("piecewise expression %s is undefined at this point, no condition holds", pw.ToSExp().String())
+}
+
+func lookup(x Symbol, env map[Symbol]float64) (float64, error) {
+	v, ok := env[x]
+	if !ok {
+		return 0, fmt.Errorf("no value for symbol %s", x)
+	}
+	return v, nil
+}
+
+// Real power of x, odd roots of negative numbers are taken as negative reals
+func rationalPower(x float64, n Rational) (float64, error) {
+	if x == 0 && n.Sign() < 0 {
+		return 0, fmt.Errorf("division by zero raising 0 to %s", n)
+	}
+	num, den := n.Frac()
+	if x >= 0 || den.IsInt64() && den.Int64() == 1 {
+		return math.Pow(x, n.Float64()), nil
+	}
+	if den.Bit(0) == 0 {
+		return 0, fmt.Errorf("negative base %v has no real power %s", x, n)
+	}
+	ret := math.Pow(-x, n.Float64())
+	if num.Bit(0) != 0 {
+		ret = -ret
+	}
+	return ret, nil
+}
+
+// Real power of x for an evaluated symbolic exponent
+func realPower(x, n float64) (float64, error) {
+	if x == 0 && n < 0 {
+		return 0, fmt.Errorf("division by zero raising 0 to %v", n)
+	}
+	if x < 0 && n != math.Trunc(n) {
+		return 0, fmt.Errorf("negative base %v has no real power %v", x, n)
+	}
+	return math.Pow(x, n), nil
+}
@@ -0,0 +1,45 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestEvaluate(t *testing.T) {
+	poly := polyFromString(t, "( + ( * a ( ^ x 2 ) ) ( * b ( ^ x n ) ) ( sqrt ( + ( ^ x 2 ) 9 ) ) 1/2 )", "a", "b", "n")
+	v, err := Evaluate(poly, map[Symbol]float64{"x": 4, "a": 2, "b": -1, "n": 1})
+	assert.NoError(t, err)
+	assert.InDelta(t, 2*16-4+5+0.5, v, 1e-9)
+
+	_, err = Evaluate(poly, map[Symbol]float64{"x": 4})
+	assert.Error(t, err, "unbound parameters")
+
+	// odd roots of negative numbers are real
+	v, err = Evaluate(polyFromString(t, "( ^ x 2/3 )"), map[Symbol]float64{"x": -8})
+	assert.NoError(t, err)
+	assert.InDelta(t, 4, v, 1e-9)
+	v, err = Evaluate(polyFromString(t, "( ^ x -1/3 )"), map[Symbol]float64{"x": -8})
+	assert.NoError(t, err)
+	assert.InDelta(t, -0.5, v, 1e-9)
+}
+
+func TestEvaluateRejectsNegativeBases(t *testing.T) {
+	_, err := Evaluate(polyFromString(t, "( sqrt x )"), map[Symbol]float64{"x": -1})
+	assert.Error(t, err)
+	_, err = Evaluate(polyFromString(t, "( sqrt ( + x 1 ) )"), map[Symbol]float64{"x": -2})
+	assert.Error(t, err)
+	_, err = Evaluate(polyFromString(t, "( ^ x n )", "n"), map[Symbol]float64{"x": -2, "n": 0.5})
+	assert.Error(t, err)
+	_, err = Evaluate(polyFromString(t, "( ^ x -1 )"), map[Symbol]float64{"x": 0})
+	assert.Error(t, err)
+
+	// even powers make the base non negative
+	v, err := Evaluate(polyFromString(t, "( sqrt ( ^ x 2 ) )"), map[Symbol]float64{"x": -3})
+	assert.NoError(t, err)
+	assert.InDelta(t, 3, v, 1e-9)
+	v, err = Evaluate(polyFromString(t, "( ^ x n )", "n"), map[Symbol]float64{"x": -2, "n": 3})
+	assert.NoError(t, err)
+	assert.InDelta(t, -8, v, 1e-9)
+}
@@ -0,0 +1,181 @@
+package symdiff
+
+import (
+	"sort"
+)
+
+/*
+   Expansion
+
+   Products of sums and powers of sums and products with positive integer
+   exponents are multiplied out into a flat sum, multinomially for powers.
+   Powers of the same variable within a term are multiplied, factors are
+   ordered as in Canonicalize and like terms are added together.
+
+     ( * 1 ( + ( ^ x 1 ) 1 ) ( + ( ^ y 1 ) -1 ) )
+       ==> ( + ( * -1 ( ^ x 1 ) ) ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( ^ y 1 ) -1 )
+     ( ^ ( + ( ^ x 1 ) 1 ) 2 ) ==> ( + ( * 2 ( ^ x 1 ) ) ( ^ x 2 ) 1 )
+
+   Other powers, abs, sign and piecewise expressions are kept as factors
+   without looking inside them.
+*/
+
+func Expand(poly PolyExp) (*PolyExp, error) {
+	if err := poly.check(); err != nil {
+		return nil, err
+	}
+	ts, err := expandTerm(poly, func(f PolyExp) (PolyExp, error) {
+		return f, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return joinTerms(collectTerms(ts)), nil
+}
+
+// Coefficient times sorted factors, one term of an expansion
+type expandedTerm struct {
+	a []coeffTerm
+	// sorted, see lessFactor
+	factors []PolyExp
+}
+
+// Expand an expression into terms, compounds are prepared by compound before
+// being kept as factors or expanded when they are powers
+func expandTerm(t PolyExp, compound func(PolyExp) (PolyExp, error)) ([]expandedTerm, error) {
+	switch {
+	case t.isCoefficient():
+		return []expandedTerm{{a: expandCoefficient(t)}}, nil
+	case t.IsMon():
+		return []expandedTerm{{a: []coeffTerm{{k: Integer(1)}}, factors: []PolyExp{t}}}, nil
+	case t.IsSum():
+		var ret []expandedTerm
+		for _, term := range t.sum().Term() {
+			ts, err := expandTerm(term, compound)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, ts...)
+		}
+		return ret, nil
+	case t.IsProduct():
+		l, fs := t.product().Term()
+		ret := []expandedTerm{{a: expandCoefficient(l)}}
+		for _, f := range fs {
+			ts, err := expandTerm(f, compound)
+			if err != nil {
+				return nil, err
+			}
+			ret = multiplyTerms(ret, ts)
+		}
+		return ret, nil
+	}
+	f, err := compound(t)
+	if err != nil {
+		return nil, err
+	}
+	if !f.isCompound() {
+		return expandTerm(f, compound)
+	}
+	if pow := f.power(); pow != nil && pow.n.IsInteger() && pow.n.Sign() > 0 && (pow.b.IsSum() || pow.b.IsProduct()) {
+		b, err := expandTerm(*pow.b, compound)
+		if err != nil {
+			return nil, err
+		}
+		return powerTerms(b, pow.n.Int()), nil
+	}
+	return []expandedTerm{{a: []coeffTerm{{k: Integer(1)}}, factors: []PolyExp{f}}}, nil
+}
+
+// Distribute the product of two expansions
+func multiplyTerms(ls, rs []expandedTerm) []expandedTerm {
+	ret := make([]expandedTerm, 0, len(ls)*len(rs))
+	for _, l := range ls {
+		for _, r := range rs {
+			factors := make([]PolyExp, 0, len(l.factors)+len(r.factors))
+			factors = append(append(factors, l.factors...), r.factors...)
+			ret = append(ret, expandedTerm{
+				a:       multiplyCoefficients(l.a, r.a),
+				factors: mergePowers(factors),
+			})
+		}
+	}
+	return ret
+}
+
+// Expansion of ts^n by squaring, collecting like terms after every
+// multiplication, which gives the multinomial coefficients
+func powerTerms(ts []expandedTerm, n int) []expandedTerm {
+	ret := []expandedTerm{{a: []coeffTerm{{k: Integer(1)}}}}
+	base := collectTerms(ts)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			ret = collectTerms(multiplyTerms(ret, base))
+		}
+		if n > 1 {
+			base = collectTerms(multiplyTerms(base, base))
+		}
+	}
+	return ret
+}
+
+// Like terms added together, ordered by their factors with the constant term
+// last.  Cancelled terms are dropped.
+func collectTerms(ts []expandedTerm) []expandedTerm {
+	coefficients := make(map[string]coefficient)
+	factors := make(map[string][]PolyExp)
+	for _, t := range ts {
+		key := factorsKey(t.factors)
+		if _, ok := coefficients[key]; !ok {
+			coefficients[key] = make(coefficient)
+			factors[key] = t.factors
+		}
+		coefficients[key].add(t.a)
+	}
+	ret := make([]expandedTerm, 0, len(coefficients))
+	for key, c := range coefficients {
+		a := make([]coeffTerm, 0, len(c))
+		for _, ct := range c {
+			if !ct.k.IsZero() {
+				a = append(a, ct)
+			}
+		}
+		if len(a) > 0 {
+			ret = append(ret, expandedTerm{a: a, factors: factors[key]})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if len(ret[i].factors) == 0 || len(ret[j].factors) == 0 {
+			return len(ret[j].factors) == 0 && len(ret[i].factors) != 0
+		}
+		return lessFactors(ret[i].factors, ret[j].factors)
+	})
+	return ret
+}
+
+// Sum of collected terms, 0 when there are none
+func joinTerms(ts []expandedTerm) *PolyExp {
+	ret := make([]PolyExp, 0, len(ts))
+	for _, t := range ts {
+		c := make(coefficient)
+		c.add(t.a)
+		switch len(t.factors) {
+		case 0:
+			ret = append(ret, c.terms()...)
+		case 1:
+			ret = append(ret, c.times(t.factors[0])...)
+		default:
+			ret = append(ret, PolyExp{
+				e: &ProductExp{
+					l:  Join(c.terms()),
+					r:  &t.factors[0],
+					fs: t.factors[1:],
+				},
+			})
+		}
+	}
+	if len(ret) == 0 {
+		ret = append(ret, Zero())
+	}
+	return Join(ret)
+}
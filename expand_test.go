@@ -0,0 +1,61 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestExpand(t *testing.T) {
+	cases := []struct {
+		raw, expected string
+	}{
+		{"( * 1 ( + ( ^ x 1 ) 1 ) ( + ( ^ x 1 ) -1 ) )", "( + ( ^ x 2 ) -1 )"},
+		{"( ^ ( + ( ^ x 1 ) ( ^ y 1 ) ) 2 )", "( + ( * 2 ( ^ x 1 ) ( ^ y 1 ) ) ( ^ x 2 ) ( ^ y 2 ) )"},
+		{"( ^ ( + ( ^ x 1 ) 1 ) 3 )", "( + ( * 3 ( ^ x 1 ) ) ( * 3 ( ^ x 2 ) ) ( ^ x 3 ) 1 )"},
+		{"( * a ( + ( ^ x 1 ) b ) )", "( + ( * a ( ^ x 1 ) ) ( * a b ) )"},
+		{"( ^ ( * 2 ( ^ x 1 ) ( + ( ^ y 1 ) 1 ) ) 2 )", "( + ( * 4 ( ^ x 2 ) ) ( * 8 ( ^ x 2 ) ( ^ y 1 ) ) ( * 4 ( ^ x 2 ) ( ^ y 2 ) ) )"},
+		// other compounds are kept unexpanded
+		{"( * 1 ( abs ( + ( ^ x 1 ) 1 ) ) ( + ( ^ x 1 ) 1 ) )", "( + ( * 1 ( ^ x 1 ) ( abs ( + ( ^ x 1 ) 1 ) ) ) ( abs ( + ( ^ x 1 ) 1 ) ) )"},
+		{"( ^ ( + ( ^ x 1 ) 1 ) -1 )", "( ^ ( + ( ^ x 1 ) 1 ) -1 )"},
+		{"( + ( * 1 ( + ( ^ x 1 ) 1 ) ( + ( ^ x 1 ) -1 ) ) ( * -1 ( ^ x 2 ) ) 1 )", "0"},
+	}
+	for _, c := range cases {
+		out, err := Expand(polyFromString(t, c.raw, "a", "b"))
+		require.NoError(t, err, c.raw)
+		assert.Equal(t, c.expected, out.ToSExp().String(), c.raw)
+	}
+
+	_, err := Expand(PolyExp{})
+	assert.Error(t, err)
+}
+
+func TestExpandMultinomial(t *testing.T) {
+	// coefficients of (x + y + 1)^4 sum to 3^4
+	out, err := Expand(polyFromString(t, "( ^ ( + ( ^ x 1 ) ( ^ y 1 ) 1 ) 4 )"))
+	require.NoError(t, err)
+	assert.Len(t, Flatten(*out), 15)
+	v, err := Evaluate(*out, map[Symbol]float64{"x": 1, "y": 1})
+	require.NoError(t, err)
+	assert.Equal(t, 81.0, v)
+	eq, err := Equivalent(*out, polyFromString(t, "( ^ ( + ( ^ x 1 ) ( ^ y 1 ) 1 ) 4 )"))
+	require.NoError(t, err)
+	assert.True(t, eq.Equivalent)
+}
+
+func TestSimplifyExpanded(t *testing.T) {
+	poly := polyFromString(t, "( + ( * 2 ( ^ ( + ( ^ x 1 ) 1 ) 2 ) ) ( * -2 ( ^ x 1 ) ) 0 )")
+	s, err := Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * -2 ( ^ x 1 ) ) ( * 2 ( ^ ( + ( ^ x 1 ) 1 ) 2 ) ) )", s.ToSExp().String())
+	s, err = SimplifyExpanded(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 2 ( ^ x 1 ) ) ( * 2 ( ^ x 2 ) ) 2 )", s.ToSExp().String())
+
+	// other optional stages stay disabled
+	s, err = SimplifyExpanded(polyFromString(t, "( / ( + ( ^ x 2 ) -1 ) ( + ( ^ x 1 ) 1 ) )"))
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 1 ( ^ x 2 ) ( ^ ( + ( ^ x 1 ) 1 ) -1 ) ) ( * -1 ( ^ ( + ( ^ x 1 ) 1 ) -1 ) ) )", s.ToSExp().String())
+}
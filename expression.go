@@ -2,7 +2,6 @@ package symdiff
 
 import (
 	"fmt"
-	"strconv"
 	"unicode"
 )
 
@@ -63,7 +62,7 @@ import (
       - we should do a specialized version of chain rule for power expressions
       - do this plus product rule then we get rational functions and that's cool
       - good goal to shoot for
-      - ( / <poly-expr> <poly-expr>) sugar for this internall use product and power expressions
+      - ( / <poly-expr> <poly-expr>) sugar for this internall use product and power expressions DONE
    4. Infinite sums
       - (+inf n (' 1 x n) ), bind a summation variable n and create a term
       - derivative treams n as a constant
@@ -92,9 +91,9 @@ import (
    3. Simplifications of richer expressions
      - Multiply out products
      - Multiply out power expressions
-     - Do polynomial division on rational expressions
+     - Do polynomial division on rational expressions DONE
 
-   4. Expose simplification rules to the repl as a command
+   4. Expose simplification rules to the repl as a command DONE
 
 
 */
@@ -103,11 +102,27 @@ import (
 
    --updated grammar--
 
-   <poly exp>  ::= <sum exp> | <monomial exp> | <product exp> | <constant exp>
+   <poly exp>  ::= <sum exp> | <monomial exp> | <product exp> | <constant exp> | <param exp> | <power exp>
+                 | <abs exp> | <sign exp> | <piecewise exp>        see piecewise.go
    <sum exp> ::= ( sum <poly exp> ... <poly exp> )
-   <monomial exp> ::= ( ^ <symbol> <int> )
-   <product exp> ::= ( * <constant> <poly exp> )
-   <constant exp> ::= int
+   <monomial exp> ::= ( ^ <symbol> <exponent> )
+   <product exp> ::= ( * <coefficient> <poly exp> ... <poly exp> )
+   <constant exp> ::= <rational>
+   <param exp> ::= <symbol>
+   <power exp> ::= ( ^ <poly exp> <rational> )     base is not a symbol
+
+   <exponent> ::= <rational> | <coefficient>
+   <coefficient> ::= <constant exp> | <param exp> | ( sum <coefficient> ... <coefficient> ) | ( * <coefficient> <coefficient> )
+
+   coefficients and exponents are expressions over parameters only, they never contain
+   monomials and are treated as constants by differentiation.  An exponent may not
+   mention the symbol of its own monomial
+
+   products of several factors are only simplified by distributing their coefficient
+
+   parameters are declared when parsing, see ParseParams, and written with their bare symbol.
+   Other bare symbols are variables, x is read as ( ^ x 1 )
+   a x^2 + b x + c, a b c declared ==> ( + ( * a ( ^ x 2 ) ) ( * b ( ^ x 1 ) ) c )
 
    simplification logic
    - de nest all sums into one flat sum expression
@@ -123,10 +138,13 @@ import (
 
    <symbol>       ::= alphabetical string
    <int>          ::= integer string
+   <rational>     ::= <int> | <int>/<int>
 
 Syntactic sugar:
 sum :=  +
 mon :=  ‘
+( sqrt <poly exp> ) := ( ^ <poly exp> 1/2 )
+( / <poly exp> <poly exp> ) := ( * 1 <poly exp> ( ^ <poly exp> -1 ) )     see quotient.go
 
 Example
 
@@ -162,6 +180,8 @@ const MonomialSugarKeyWord = "^"
 const ProductKeyWord = "prod"
 const ProductSugarKeyWord = "*"
 const DeprecatedMonomialSyntax = "'"
+const SqrtKeyWord = "sqrt"
+const QuotientKeyWord = "/"
 
 // Valid atom strings that are not alphanumeric
 var SpecialAtoms map[string]struct{}
@@ -173,6 +193,7 @@ func init() {
 	SpecialAtoms[MonomialSugarKeyWord] = struct{}{}
 	SpecialAtoms[ProductSugarKeyWord] = struct{}{}
 	SpecialAtoms[DeprecatedMonomialSyntax] = struct{}{}
+	SpecialAtoms[QuotientKeyWord] = struct{}{}
 
 	Rainbow = make([]int, 6)
 	Rainbow[1] = 124
@@ -185,12 +206,18 @@ func init() {
 }
 
 type ConstantExp struct {
-	c int
+	c Rational
+}
+
+// Getter for constant value
+// Fields are private to restrict setting to parsing
+func (c *ConstantExp) Term() Rational {
+	return c.c
 }
 
 func (c *ConstantExp) ToSExp() SExp {
 	a := new(Atom)
-	*a = Atom(fmt.Sprintf("%d", c.c))
+	*a = Atom(c.c.String())
 	return SExp{
 		Atom: a,
 	}
@@ -200,7 +227,7 @@ func (c *ConstantExp) Parse(s SExp) error {
 	if s.Atom == nil {
 		return fmt.Errorf("invalid S expression %s, cannot parse as constant polynomial", s.String())
 	}
-	a, err := strconv.Atoi(string(*s.Atom))
+	a, err := ParseRational(string(*s.Atom))
 	if err != nil {
 		return fmt.Errorf("%s failed to parse constant %s", err, s.String())
 	}
@@ -209,18 +236,80 @@ func (c *ConstantExp) Parse(s SExp) error {
 	return nil
 }
 
+func (c *ConstantExp) clone() Expr {
+	ret := *c
+	return &ret
+}
+
+func (c *ConstantExp) mentions(v Symbol) bool {
+	return false
+}
+
+func (c *ConstantExp) isCoefficient() bool {
+	return true
+}
+
+// Declared symbol standing for a constant parameter of the polynomial, i.e. the n in ( ^ x n )
+type ParamExp struct {
+	a Symbol
+}
+
+// Getter for parameter symbol
+// Fields are private to restrict setting to parsing
+func (a *ParamExp) Term() Symbol {
+	return a.a
+}
+
+func (a *ParamExp) ToSExp() SExp {
+	return NewAtom(string(a.a))
+}
+
+func (a *ParamExp) Parse(s SExp) error {
+	if s.Atom == nil || !IsSymbol(string(*s.Atom)) {
+		return fmt.Errorf("invalid S expression %s, cannot parse as parameter", s.String())
+	}
+	a.a = Symbol(*s.Atom)
+	return nil
+}
+
+func (a *ParamExp) clone() Expr {
+	ret := *a
+	return &ret
+}
+
+func (a *ParamExp) mentions(v Symbol) bool {
+	return a.a == v
+}
+
+func (a *ParamExp) isCoefficient() bool {
+	return true
+}
+
 type ProductExp struct {
-	l *ConstantExp
+	// Invariant: l is a coefficient expression, see isCoefficient
+	l *PolyExp
 	r *PolyExp
+	// Further factors multiplied with r, nil for the usual ( * a p ) product
+	fs []PolyExp
+}
+
+// Getter for coefficient and all factors of the product
+// Fields are private to restrict setting to parsing
+func (p *ProductExp) Term() (PolyExp, []PolyExp) {
+	return *p.l, append([]PolyExp{*p.r}, p.fs...)
 }
 
 func (p *ProductExp) ToSExp() SExp {
+	sub := []SExp{
+		NewAtom("*"),
+		p.l.ToSExp(),
+		p.r.ToSExp(),
+	}
+	for _, f := range p.fs {
+		sub = append(sub, f.ToSExp())
+	}
 	return SExp{
-		List: []SExp{
-			NewAtom("*"),
-			p.l.ToSExp(),
-			p.r.ToSExp(),
-		},
+		List: sub,
 	}
 
 }
@@ -233,32 +322,98 @@ func (p *ProductExp) match(sexp SExp) bool {
 }
 
 func (p *ProductExp) Parse(sexp SExp) error {
-	if len(sexp.List) != 3 || !p.match(sexp.List[0]) {
+	return p.parse(sexp, nil)
+}
+
+func (p *ProductExp) parse(sexp SExp, params map[Symbol]bool) error {
+	if len(sexp.List) < 3 || !p.match(sexp.List[0]) {
 		return fmt.Errorf("invalid SExp, cannot parse as polynomial product %s", sexp.String())
 	}
-	var constExp ConstantExp
-	if err := constExp.Parse(sexp.List[1]); err != nil {
-		return fmt.Errorf("%s, failed to parse left multiplicand (%s) as constant polynomial", err, sexp.List[1].String())
+	var coeff PolyExp
+	if err := coeff.parse(sexp.List[1], params); err != nil {
+		return fmt.Errorf("%s, failed to parse left multiplicand (%s) as coefficient", err, sexp.List[1].String())
 	}
-	p.l = &constExp
+	if !coeff.isCoefficient() {
+		// undeclared symbols are variables, a left multiplicand that is only
+		// no coefficient because of them is a factor, ( * a x ) is ( * 1 a x )
+		var declared PolyExp
+		if err := declared.parse(sexp.List[1], declareAll(sexp.List[1], params)); err != nil || !declared.isCoefficient() {
+			return fmt.Errorf("left multiplicand (%s) is not a coefficient, coefficients may not contain monomials", sexp.List[1].String())
+		}
+		return p.parse(SExp{List: append([]SExp{sexp.List[0], NewAtom("1")}, sexp.List[1:]...)}, params)
+	}
+	p.l = &coeff
 	var poly PolyExp
-	if err := poly.Parse(sexp.List[2]); err != nil {
+	if err := poly.parse(sexp.List[2], params); err != nil {
 		return fmt.Errorf("%s, failed to parse sub expression %s as polynomials", err, sexp.List[2].String())
 	}
 	p.r = &poly
+	for _, exp := range sexp.List[3:] {
+		var f PolyExp
+		if err := f.parse(exp, params); err != nil {
+			return fmt.Errorf("%s, failed to parse sub expression %s as polynomials", err, exp.String())
+		}
+		p.fs = append(p.fs, f)
+	}
 
 	return nil
 }
 
+func (p *ProductExp) clone() Expr {
+	l, r := p.l.Clone(), p.r.Clone()
+	return &ProductExp{l: &l, r: &r, fs: cloneAll(p.fs)}
+}
+
+func (p *ProductExp) mentions(v Symbol) bool {
+	for _, f := range p.fs {
+		if f.mentions(v) {
+			return true
+		}
+	}
+	return p.l.mentions(v) || p.r.mentions(v)
+}
+
+func (p *ProductExp) isCoefficient() bool {
+	for _, f := range p.fs {
+		if !f.isCoefficient() {
+			return false
+		}
+	}
+	return p.l.isCoefficient() && p.r.isCoefficient()
+}
+
 type MonomialExp struct {
 	x Symbol
-	n int
+	n Rational
+	// Symbolic exponent, nil when the exponent is the rational n
+	e *PolyExp
 }
 
 // Getter for all fields constituting monomial term
 // Fields are private to restrict setting to parsing
+// Only meaningful for integer exponents, see RationalExponent and Exponent
+// Symbolic, fractional and exponents that do not fit an int are reported as 0
 func (m *MonomialExp) Term() (Symbol, int) {
-	return m.x, m.n
+	if m.e != nil || !m.n.IsInt() {
+		return m.x, 0
+	}
+	return m.x, m.n.Int()
+}
+
+// Getter for the rational exponent of the monomial, false for symbolic exponents
+func (m *MonomialExp) RationalExponent() (Rational, bool) {
+	if m.e != nil {
+		return Rational{}, false
+	}
+	return m.n, true
+}
+
+// Getter for the exponent of the monomial as a coefficient expression
+func (m *MonomialExp) Exponent() PolyExp {
+	if m.e != nil {
+		return *m.e
+	}
+	return PolyExp{e: &ConstantExp{c: m.n}}
 }
 
 // Match monomial to SExp head Atom
@@ -270,36 +425,140 @@ func (m *MonomialExp) match(s SExp) bool {
 }
 
 func (m *MonomialExp) ToSExp() SExp {
+	exponent := NewAtom(m.n.String())
+	if m.e != nil {
+		exponent = m.e.ToSExp()
+	}
 	return SExp{
 		List: []SExp{
 			NewAtom("^"),
 			NewAtom(string(m.x)),
-			NewAtom(fmt.Sprintf("%d", m.n)),
+			exponent,
 		},
 	}
 }
 
 func (m *MonomialExp) Parse(s SExp) error {
-	if len(s.List) != 3 && !m.match(s.List[0]) {
+	return m.parse(s, nil)
+}
+
+func (m *MonomialExp) parse(s SExp, params map[Symbol]bool) error {
+	if len(s.List) != 3 || !m.match(s.List[0]) {
 		return fmt.Errorf("invalid SExp, cannot parse as monomial %s", s.String())
 	}
-	for _, sexp := range s.List[1:] {
-		if sexp.Atom == nil {
-			return fmt.Errorf("invalid SExp, cannot parse as monomial %s", s.String())
-		}
-	}
-	if !IsSymbol(string(*s.List[1].Atom)) {
+	if s.List[1].Atom == nil || !IsSymbol(string(*s.List[1].Atom)) {
 		return fmt.Errorf("failed to parse variable, not a valid symbol for monomial %s", s.String())
 	}
 	m.x = Symbol(*s.List[1].Atom)
-	n, err := strconv.Atoi(string(*s.List[2].Atom))
-	if err != nil {
+	if params[m.x] {
+		return fmt.Errorf("%s is declared a parameter, cannot parse as monomial %s", m.x, s.String())
+	}
+	if s.List[2].Atom != nil {
+		if n, err := ParseRational(string(*s.List[2].Atom)); err == nil {
+			m.n = n
+			return nil
+		}
+	}
+
+	// symbolic exponent
+	var e PolyExp
+	if err := e.parse(s.List[2], params); err != nil {
 		return fmt.Errorf("failed to parse exponent %s for monomial %s", err, s.String())
 	}
-	m.n = n
+	if !e.isCoefficient() {
+		return fmt.Errorf("exponent %s of monomial %s may not contain monomials, undeclared symbols are variables", s.List[2].String(), s.String())
+	}
+	if e.mentions(m.x) {
+		return fmt.Errorf("exponent %s of monomial %s depends on its own variable", s.List[2].String(), s.String())
+	}
+	m.e = &e
+	return nil
+}
+
+func (m *MonomialExp) clone() Expr {
+	ret := *m
+	if m.e != nil {
+		e := m.e.Clone()
+		ret.e = &e
+	}
+	return &ret
+}
+
+func (m *MonomialExp) mentions(v Symbol) bool {
+	return m.x == v || (m.e != nil && m.e.mentions(v))
+}
+
+func (m *MonomialExp) isCoefficient() bool {
+	return false
+}
+
+// Power of an expression that is not a bare symbol, i.e. ( ^ ( + ( ^ x 2 ) 1 ) 1/2 )
+type PowerExp struct {
+	b *PolyExp
+	n Rational
+}
+
+// Getter for base and exponent of the power
+// Fields are private to restrict setting to parsing
+func (pow *PowerExp) Term() (PolyExp, Rational) {
+	return *pow.b, pow.n
+}
+
+func (pow *PowerExp) match(sexp SExp) bool {
+	if sexp.Atom == nil {
+		return false
+	}
+	return *sexp.Atom == Atom(MonomialKeyWord) || *sexp.Atom == Atom(MonomialSugarKeyWord)
+}
+
+func (pow *PowerExp) ToSExp() SExp {
+	return SExp{
+		List: []SExp{
+			NewAtom("^"),
+			pow.b.ToSExp(),
+			NewAtom(pow.n.String()),
+		},
+	}
+}
+
+func (pow *PowerExp) Parse(sexp SExp) error {
+	return pow.parse(sexp, nil)
+}
+
+func (pow *PowerExp) parse(sexp SExp, params map[Symbol]bool) error {
+	if len(sexp.List) != 3 || !pow.match(sexp.List[0]) {
+		return fmt.Errorf("invalid SExp, cannot parse as power %s", sexp.String())
+	}
+	var b PolyExp
+	if err := b.parse(sexp.List[1], params); err != nil {
+		return fmt.Errorf("%s, failed to parse base %s of power %s", err, sexp.List[1].String(), sexp.String())
+	}
+	pow.b = &b
+	if sexp.List[2].Atom == nil {
+		return fmt.Errorf("exponent of power %s must be rational", sexp.String())
+	}
+	n, err := ParseRational(string(*sexp.List[2].Atom))
+	if err != nil {
+		return fmt.Errorf("%s, failed to parse exponent of power %s", err, sexp.String())
+	}
+	pow.n = n
 	return nil
 }
 
+func (pow *PowerExp) clone() Expr {
+	b := pow.b.Clone()
+	return &PowerExp{b: &b, n: pow.n}
+}
+
+func (pow *PowerExp) mentions(v Symbol) bool {
+	return pow.b.mentions(v)
+}
+
+// Powers of parameters are coefficients
+func (pow *PowerExp) isCoefficient() bool {
+	return pow.b.IsParam()
+}
+
 type SumExp struct {
 	ps []PolyExp
 }
@@ -307,7 +566,7 @@ type SumExp struct {
 // Getter for all sum terms
 // Fields are private to restrict setting to parsing
 func (s *SumExp) Term() []PolyExp {
-	return s.ps
+	return append([]PolyExp{}, s.ps...)
 }
 
 func (sum *SumExp) match(sexp SExp) bool {
@@ -329,12 +588,16 @@ func (sum *SumExp) ToSExp() SExp {
 }
 
 func (sum *SumExp) Parse(sexp SExp) error {
+	return sum.parse(sexp, nil)
+}
+
+func (sum *SumExp) parse(sexp SExp, params map[Symbol]bool) error {
 	if len(sexp.List) < 3 || !sum.match(sexp.List[0]) {
 		return fmt.Errorf("invalid SExp, cannot parse as polynomial sum %s", sexp.String())
 	}
 	for _, exp := range sexp.List[1:] {
 		var poly PolyExp
-		if err := poly.Parse(exp); err != nil {
+		if err := poly.parse(exp, params); err != nil {
 			return fmt.Errorf("%s, failed to parse sub expression %s as polynomial while parsing sum exp %s", err, exp.String(), sexp.String())
 		}
 		sum.ps = append(sum.ps, poly)
@@ -342,63 +605,241 @@ func (sum *SumExp) Parse(sexp SExp) error {
 	return nil
 }
 
+func (sum *SumExp) clone() Expr {
+	return &SumExp{ps: cloneAll(sum.ps)}
+}
+
+func (sum *SumExp) mentions(v Symbol) bool {
+	for i := range sum.ps {
+		if sum.ps[i].mentions(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sum *SumExp) isCoefficient() bool {
+	for i := range sum.ps {
+		if !sum.ps[i].isCoefficient() {
+			return false
+		}
+	}
+	return true
+}
+
+// Node of an expression tree.  Every node kind implements Expr so that
+// algorithms applying to all expressions dispatch on the node instead of
+// switching over kinds, a new kind only needs these methods and parsing.
+type Expr interface {
+	ToSExp() SExp
+	Differentiate(v Symbol) (*PolyExp, error)
+	// Derivative recording its steps in tr, see DifferentiateTrace
+	differentiate(v Symbol, tr *Trace) (*PolyExp, error)
+	// Value at the point given by env, see Evaluate
+	Evaluate(env map[Symbol]float64) (float64, error)
+	// Structural hash, see Hash
+	Hash() uint64
+
+	// Deep copy sharing no nodes
+	clone() Expr
+	// Report whether symbol v occurs anywhere in the expression
+	mentions(v Symbol) bool
+	// Coefficients are built from constants and parameters only
+	isCoefficient() bool
+	// Sub expressions in printed order and a copy of the node built from
+	// replacements for them, see Walk and Rewrite
+	children() []PolyExp
+	rebuild(children []PolyExp) (Expr, error)
+	// Name of the rule differentiating the node, see DifferentiateTrace
+	derivativeRule(v Symbol) string
+	// Kind and data of the node other than its children, nodes with equal
+	// labels and equal children are equal
+	label() []string
+}
+
+var _ Expr = (*SumExp)(nil)
+var _ Expr = (*MonomialExp)(nil)
+var _ Expr = (*ProductExp)(nil)
+var _ Expr = (*ConstantExp)(nil)
+var _ Expr = (*ParamExp)(nil)
+var _ Expr = (*PowerExp)(nil)
+var _ Expr = (*AbsExp)(nil)
+var _ Expr = (*SignExp)(nil)
+var _ Expr = (*PiecewiseExp)(nil)
+
+// Expressions are immutable once parsed.  Transformations like Simplify and
+// Differentiate build new nodes and may share unchanged subtrees with their
+// inputs but never modify them, so parsed expressions can be reused freely.
+// Use Clone for a deep copy that shares nothing.
+// PolyExp wraps the Expr node at the root of the expression
 type PolyExp struct {
-	// Invariant: union type, at most one field allowed to be populated the other must be nil
-	s *SumExp
-	m *MonomialExp
-	c *ConstantExp
-	p *ProductExp
+	// Invariant: populated by parsing or a builder, nil only for the zero value
+	e Expr
+}
+
+// Getter for the root node of the expression
+func (p *PolyExp) Expr() Expr {
+	return p.e
 }
 
 func (p *PolyExp) IsSum() bool {
-	return p.s != nil
+	return p.sum() != nil
 }
 
 func (p *PolyExp) IsMon() bool {
-	return p.m != nil
+	return p.mon() != nil
 }
 
 func (p *PolyExp) IsProduct() bool {
-	return p.p != nil
+	return p.product() != nil
 }
 
 func (p *PolyExp) IsConstant() bool {
-	return p.c != nil
+	return p.constant() != nil
+}
+
+func (p *PolyExp) IsParam() bool {
+	return p.param() != nil
+}
+
+func (p *PolyExp) IsPower() bool {
+	return p.power() != nil
+}
+
+func (p *PolyExp) IsAbs() bool {
+	return p.abs() != nil
+}
+
+func (p *PolyExp) IsSign() bool {
+	return p.sign() != nil
+}
+
+func (p *PolyExp) IsPiecewise() bool {
+	return p.piecewise() != nil
 }
 
 func (p *PolyExp) Sum() (*SumExp, error) {
-	if p.s == nil {
+	if !p.IsSum() {
 		return nil, fmt.Errorf("polynomial is not a sum expression")
 	}
-	return p.s, nil
+	return p.sum(), nil
 }
 
 func (p *PolyExp) Mon() (*MonomialExp, error) {
-	if p.m == nil {
+	if !p.IsMon() {
 		return nil, fmt.Errorf("polynomial is not a monomial expression")
 	}
-	return p.m, nil
+	return p.mon(), nil
 }
 
-func (p *PolyExp) check() error {
-	var nilCount int
-	if p.s == nil {
-		nilCount++
+func (p *PolyExp) Param() (*ParamExp, error) {
+	if !p.IsParam() {
+		return nil, fmt.Errorf("polynomial is not a parameter expression")
 	}
-	if p.m == nil {
-		nilCount++
+	return p.param(), nil
+}
+
+func (p *PolyExp) Power() (*PowerExp, error) {
+	if !p.IsPower() {
+		return nil, fmt.Errorf("polynomial is not a power expression")
 	}
-	if p.p == nil {
-		nilCount++
+	return p.power(), nil
+}
+
+func (p *PolyExp) Abs() (*AbsExp, error) {
+	if !p.IsAbs() {
+		return nil, fmt.Errorf("polynomial is not an absolute value expression")
 	}
-	if p.c == nil {
-		nilCount++
+	return p.abs(), nil
+}
+
+func (p *PolyExp) Sign() (*SignExp, error) {
+	if !p.IsSign() {
+		return nil, fmt.Errorf("polynomial is not a sign expression")
 	}
+	return p.sign(), nil
+}
 
-	if nilCount < 3 {
-		return fmt.Errorf("overpopulated PolyExp")
+func (p *PolyExp) Piecewise() (*PiecewiseExp, error) {
+	if !p.IsPiecewise() {
+		return nil, fmt.Errorf("polynomial is not a piecewise expression")
 	}
-	if nilCount == 4 {
+	return p.piecewise(), nil
+}
+
+// Typed views of the root node, nil when the node is of another kind
+
+func (p *PolyExp) sum() *SumExp {
+	s, _ := p.e.(*SumExp)
+	return s
+}
+
+func (p *PolyExp) mon() *MonomialExp {
+	m, _ := p.e.(*MonomialExp)
+	return m
+}
+
+func (p *PolyExp) product() *ProductExp {
+	prod, _ := p.e.(*ProductExp)
+	return prod
+}
+
+func (p *PolyExp) constant() *ConstantExp {
+	c, _ := p.e.(*ConstantExp)
+	return c
+}
+
+func (p *PolyExp) param() *ParamExp {
+	a, _ := p.e.(*ParamExp)
+	return a
+}
+
+func (p *PolyExp) power() *PowerExp {
+	w, _ := p.e.(*PowerExp)
+	return w
+}
+
+func (p *PolyExp) abs() *AbsExp {
+	abs, _ := p.e.(*AbsExp)
+	return abs
+}
+
+func (p *PolyExp) sign() *SignExp {
+	sgn, _ := p.e.(*SignExp)
+	return sgn
+}
+
+func (p *PolyExp) piecewise() *PiecewiseExp {
+	pw, _ := p.e.(*PiecewiseExp)
+	return pw
+}
+
+// Deep copy of the expression sharing no nodes with p
+func (p *PolyExp) Clone() PolyExp {
+	return PolyExp{e: p.e.clone()}
+}
+
+func cloneAll(polys []PolyExp) []PolyExp {
+	if polys == nil {
+		return nil
+	}
+	ret := make([]PolyExp, len(polys))
+	for i := range polys {
+		ret[i] = polys[i].Clone()
+	}
+	return ret
+}
+
+func (p *PolyExp) isCoefficient() bool {
+	return p.e.isCoefficient()
+}
+
+func (p *PolyExp) mentions(v Symbol) bool {
+	return p.e.mentions(v)
+}
+
+func (p *PolyExp) check() error {
+	if p.e == nil {
 		return fmt.Errorf("unpopulated PolyExp")
 	}
 	return nil
@@ -406,34 +847,84 @@ func (p *PolyExp) check() error {
 
 // invariant polyexpression is valid
 func (p *PolyExp) ToSExp() SExp {
-	if p.IsMon() {
-		return p.m.ToSExp()
-	}
-	if p.IsProduct() {
-		return p.p.ToSExp()
+	return p.e.ToSExp()
+}
+
+// populate polynomial with contents of s-expression, bare symbols are
+// variables, see ParseParams
+func (p *PolyExp) Parse(sexp SExp) error {
+	return p.ParseParams(sexp, nil)
+}
+
+// populate polynomial with contents of s-expression, bare symbols declared
+// as parameters stand for constants and the others are variables, x is read
+// as ( ^ x 1 )
+func (p *PolyExp) ParseParams(sexp SExp, params []Symbol) error {
+	declared := make(map[Symbol]bool)
+	for _, a := range params {
+		declared[a] = true
 	}
-	if p.IsConstant() {
-		return p.c.ToSExp()
+	var ret PolyExp
+	if err := ret.parse(sexp, declared); err != nil {
+		return err
 	}
+	*p = ret
+	return nil
+}
 
-	return p.s.ToSExp()
+// Declared parameters with every bare symbol of sexp added, bases of powers
+// ( ^ x n ) stay variables
+func declareAll(sexp SExp, params map[Symbol]bool) map[Symbol]bool {
+	ret := make(map[Symbol]bool)
+	for a := range params {
+		ret[a] = true
+	}
+	var m MonomialExp
+	var collect func(SExp)
+	collect = func(s SExp) {
+		if s.Atom != nil && IsSymbol(string(*s.Atom)) {
+			ret[Symbol(*s.Atom)] = true
+		}
+		for i, sub := range s.List {
+			if i == 1 && m.match(s.List[0]) && sub.Atom != nil {
+				continue
+			}
+			collect(sub)
+		}
+	}
+	collect(sexp)
+	return ret
 }
 
-// populate polynomial with contents of s-expression
-func (p *PolyExp) Parse(sexp SExp) (err error) {
+// parse with the declared parameters
+func (p *PolyExp) parse(sexp SExp, params map[Symbol]bool) (err error) {
 	defer func() {
 		if err == nil {
 			err = p.check()
 		}
 	}()
 
-	// First check for constant exp
+	// First check for atoms, declared symbols are parameters and the others variables
+	if sexp.Atom != nil && IsSymbol(string(*sexp.Atom)) {
+		if !params[Symbol(*sexp.Atom)] {
+			p.e = &MonomialExp{x: Symbol(*sexp.Atom), n: Integer(1)}
+			return nil
+		}
+		var a ParamExp
+		if err := a.Parse(sexp); err != nil {
+			return err
+		}
+		p.e = &a
+		return nil
+	}
+
+	// Remaining atoms are constant exps
 	if sexp.Atom != nil {
 		var c ConstantExp
 		if err := c.Parse(sexp); err != nil {
 			return err
 		}
-		p.c = &c
+		p.e = &c
 		return nil
 	}
 
@@ -443,24 +934,68 @@ func (p *PolyExp) Parse(sexp SExp) (err error) {
 	var s SumExp
 	var m MonomialExp
 	var prod ProductExp
+	var pow PowerExp
+	var abs AbsExp
+	var sgn SignExp
+	var pw PiecewiseExp
+
+	// ( sqrt e ) is sugar for ( ^ e 1/2 )
+	if head := sexp.List[0]; head.Atom != nil && *head.Atom == Atom(SqrtKeyWord) {
+		if len(sexp.List) != 2 {
+			return fmt.Errorf("invalid SExp, cannot parse as square root %s", sexp.String())
+		}
+		return p.parse(SExp{List: []SExp{NewAtom("^"), sexp.List[1], NewAtom("1/2")}}, params)
+	}
 
-	if s.match(sexp.List[0]) {
-		if err := s.Parse(sexp); err != nil {
-			return err
+	// ( / a b ) is sugar for ( * a ( ^ b -1 ) ) with atoms a and ( * 1 a ( ^ b -1 ) ) otherwise
+	if head := sexp.List[0]; head.Atom != nil && *head.Atom == Atom(QuotientKeyWord) {
+		if len(sexp.List) != 3 {
+			return fmt.Errorf("invalid SExp, cannot parse as quotient %s", sexp.String())
 		}
-		p.s = &s
+		inv := SExp{List: []SExp{NewAtom("^"), sexp.List[2], NewAtom("-1")}}
+		if sexp.List[1].Atom != nil {
+			return p.parse(SExp{List: []SExp{NewAtom("*"), sexp.List[1], inv}}, params)
+		}
+		return p.parse(SExp{List: []SExp{NewAtom("*"), NewAtom("1"), sexp.List[1], inv}}, params)
 	}
-	if m.match(sexp.List[0]) {
-		if err := m.Parse(sexp); err != nil {
+
+	switch head := sexp.List[0]; {
+	case s.match(head):
+		if err := s.parse(sexp, params); err != nil {
 			return err
 		}
-		p.m = &m
-	}
-	if prod.match(sexp.List[0]) {
-		if err := prod.Parse(sexp); err != nil {
+		p.e = &s
+	// Powers of bare undeclared symbols are monomials, powers of parameters are coefficients
+	case m.match(head) && len(sexp.List) > 1 && sexp.List[1].Atom != nil && IsSymbol(string(*sexp.List[1].Atom)) && !params[Symbol(*sexp.List[1].Atom)]:
+		if err := m.parse(sexp, params); err != nil {
+			return err
+		}
+		p.e = &m
+	case pow.match(head):
+		if err := pow.parse(sexp, params); err != nil {
+			return err
+		}
+		p.e = &pow
+	case abs.match(head):
+		if err := abs.parse(sexp, params); err != nil {
+			return err
+		}
+		p.e = &abs
+	case sgn.match(head):
+		if err := sgn.parse(sexp, params); err != nil {
+			return err
+		}
+		p.e = &sgn
+	case pw.match(head):
+		if err := pw.parse(sexp, params); err != nil {
+			return err
+		}
+		p.e = &pw
+	case prod.match(head):
+		if err := prod.parse(sexp, params); err != nil {
 			return err
 		}
-		p.p = &prod
+		p.e = &prod
 	}
 
 	return nil
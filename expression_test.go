@@ -240,3 +240,117 @@ func TestNoProductsOfPolynomialsYet(t *testing.T) {
 	var poly PolyExp
 	assert.Error(t, poly.Parse(sexp))
 }
+
+func TestParseSymbolicExponent(t *testing.T) {
+	poly := polyFromString(t, "( ^ x n )", "n")
+	assert.True(t, poly.IsMon())
+	exponent := polyMon(t, poly).Exponent()
+	assert.True(t, exponent.IsParam())
+	assert.Equal(t, "( ^ x n )", poly.ToSExp().String())
+
+	poly = polyFromString(t, "( ^ x ( + ( * 2 n ) m 1 ) )", "n", "m")
+	assert.Equal(t, "( ^ x ( + ( * 2 n ) m 1 ) )", poly.ToSExp().String())
+
+	var sexp SExp
+	// exponents may not depend on the monomial variable
+	require.NoError(t, sexp.Parse("( ^ x ( + x 1 ) )"))
+	var bad PolyExp
+	assert.Error(t, bad.Parse(sexp))
+	// exponents may not contain monomials
+	require.NoError(t, sexp.Parse("( ^ x ( ^ y 2 ) )"))
+	assert.Error(t, bad.Parse(sexp))
+	// monomials need a variable and an exponent
+	require.NoError(t, sexp.Parse("( ^ x )"))
+	assert.Error(t, bad.Parse(sexp))
+	require.NoError(t, sexp.Parse("( ^ x 1 2 )"))
+	assert.Error(t, bad.Parse(sexp))
+}
+
+func TestParseRationals(t *testing.T) {
+	poly := polyFromString(t, "( + 1/2 -6/4 ( ^ x -3/2 ) )")
+	assert.Equal(t, "( + 1/2 -3/2 ( ^ x -3/2 ) )", poly.ToSExp().String())
+
+	poly = polyFromString(t, "( sqrt x )")
+	assert.True(t, poly.IsMon())
+	assert.Equal(t, "( ^ x 1/2 )", poly.ToSExp().String())
+	// Term only reports integer exponents
+	x, n := polyMon(t, poly).Term()
+	assert.Equal(t, Symbol("x"), x)
+	assert.Equal(t, 0, n)
+	half, ok := polyMon(t, poly).RationalExponent()
+	require.True(t, ok)
+	assert.Equal(t, "1/2", half.String())
+	_, ok = polyMon(t, polyFromString(t, "( ^ x n )", "n")).RationalExponent()
+	assert.False(t, ok)
+
+	poly = polyFromString(t, "( sqrt ( + ( ^ x 2 ) 1 ) )")
+	assert.True(t, poly.IsPower())
+	assert.Equal(t, "( ^ ( + ( ^ x 2 ) 1 ) 1/2 )", poly.ToSExp().String())
+
+	poly = polyFromString(t, "( * 2 ( ^ x 1 ) ( sqrt ( + x 1 ) ) )")
+	assert.Equal(t, "( * 2 ( ^ x 1 ) ( ^ ( + ( ^ x 1 ) 1 ) 1/2 ) )", poly.ToSExp().String())
+
+	// undeclared symbols are variables, so are factors rather than coefficients
+	poly = polyFromString(t, "( * a x )")
+	assert.Equal(t, "( * 1 ( ^ a 1 ) ( ^ x 1 ) )", poly.ToSExp().String())
+
+	var sexp SExp
+	require.NoError(t, sexp.Parse("1/0"))
+	var bad PolyExp
+	assert.Error(t, bad.Parse(sexp))
+	var sexp2 SExp
+	require.NoError(t, sexp2.Parse("( ^ ( + x 1 ) n )"))
+	var bad2 PolyExp
+	assert.Error(t, bad2.Parse(sexp2), "powers of expressions take rational exponents")
+}
+
+func TestClone(t *testing.T) {
+	poly := polyFromString(t, "( + ( * ( + a 1/2 ) ( ^ x ( + n 1 ) ) ) ( * 2 ( ^ x 1 ) ( sqrt ( + ( ^ x 2 ) 1 ) ) ) ( abs ( ^ x 1 ) ) ( sign a ) "+hinge+" )", "a", "n")
+	clone := poly.Clone()
+	assert.Equal(t, poly, clone)
+	assert.Equal(t, poly.ToSExp().String(), clone.ToSExp().String())
+
+	// getters hand out copies
+	terms := polySum(t, poly).Term()
+	terms[0] = Zero()
+	assert.Equal(t, clone, poly)
+}
+
+func TestExprInterface(t *testing.T) {
+	for _, raw := range []string{
+		"3",
+		"a",
+		"( ^ x 3 )",
+		"( * a ( ^ x 2 ) )",
+		"( * 2 ( ^ x 1 ) ( ^ ( + ( ^ x 2 ) 1 ) 1/2 ) )",
+		"( + ( ^ x 2 ) ( * 2 ( ^ x 1 ) ) 1 )",
+		"( abs ( ^ x 1 ) )",
+		"( sign ( ^ x 1 ) )",
+		hinge,
+	} {
+		poly := polyFromString(t, raw, "a")
+		e := poly.Expr()
+		require.NotNil(t, e, raw)
+		assert.Equal(t, poly.ToSExp(), e.ToSExp())
+
+		// nodes differentiate and evaluate the same as the wrapper
+		expected, err := Differentiate("x", poly)
+		require.NoError(t, err)
+		actual, err := e.Differentiate("x")
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		env := map[Symbol]float64{"x": 3, "a": 2}
+		expectedValue, err := Evaluate(poly, env)
+		require.NoError(t, err)
+		actualValue, err := e.Evaluate(env)
+		require.NoError(t, err)
+		assert.Equal(t, expectedValue, actualValue)
+	}
+
+	var unpopulated PolyExp
+	assert.Nil(t, unpopulated.Expr())
+	assert.False(t, unpopulated.IsSum())
+	_, err := unpopulated.Sum()
+	assert.Error(t, err)
+}
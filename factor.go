@@ -0,0 +1,404 @@
+package symdiff
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+   Factorization of univariate polynomials over the integers
+
+   The polynomial is expanded and split into its rational content times a
+   primitive integer polynomial, which is then
+   - decomposed square free (Yun), f = a1 a2^2 a3^3 ...
+   - stripped of linear factors ( q x - p ) for rational roots p/q
+   - split into irreducible factors of higher degree with Kronecker's method,
+     trying integer interpolations through divisors of values of f
+
+     ( + ( * 2 ( ^ x 3 ) ) ( * -2 ( ^ x 1 ) ) ) ==>
+     ( * 2 ( + ( ^ x 1 ) -1 ) ( ^ x 1 ) ( + ( ^ x 1 ) 1 ) )
+
+   Kronecker's method is exponential in the degree, candidates are only tried
+   up to MaxKroneckerCandidates per degree and factors left at that point are
+   reported as they are.  Likewise rational roots and Kronecker candidates
+   are only searched among divisors of values up to MaxTrialDivision.  The
+   factors found are multiplied back and checked against the polynomial.
+*/
+
+// Bound on interpolation candidates tried per factor degree
+const MaxKroneckerCandidates = 100000
+
+// Bound on integers whose divisors are enumerated by trial division
+const MaxTrialDivision = 1 << 40
+
+// Factor a univariate polynomial with rational coefficients into its content
+// times powers of irreducible primitive integer polynomials, ordered by degree
+// then coefficients
+func Factor(poly PolyExp) (*PolyExp, error) {
+	if err := poly.check(); err != nil {
+		return nil, err
+	}
+	x, p, err := toUnivariate(poly)
+	if err != nil {
+		return nil, fmt.Errorf("%s, can only factor polynomials in one variable with rational coefficients", err)
+	}
+	if p.degree() < 1 {
+		ret := p.toPolyExp(x)
+		return &ret, nil
+	}
+	content, factors, err := factorUnivariate(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s, failed to factor %s", err, poly.ToSExp().String())
+	}
+
+	ret := make([]PolyExp, 0, len(factors)+1)
+	if !content.Equal(Integer(1)) {
+		ret = append(ret, Const(content))
+	}
+	for _, f := range factors {
+		b := f.p.toPolyExp(x)
+		if f.k == 1 {
+			ret = append(ret, b)
+			continue
+		}
+		if b.IsMon() {
+			ret = append(ret, PolyExp{e: &MonomialExp{x: x, n: Integer(f.k)}})
+			continue
+		}
+		ret = append(ret, PolyExp{e: &PowerExp{b: &b, n: Integer(f.k)}})
+	}
+	if len(ret) == 1 {
+		return &ret[0], nil
+	}
+	prod, err := Mul(ret...)
+	if err != nil {
+		return nil, err
+	}
+	return &prod, nil
+}
+
+// Irreducible factor raised to a multiplicity
+type upolyFactor struct {
+	p upoly
+	k int
+}
+
+// Content and irreducible primitive factors of p with their multiplicities,
+// ordered by degree then coefficients, checked to multiply back to p
+// Invariant: p.degree() >= 1
+func factorUnivariate(p upoly) (Rational, []upolyFactor, error) {
+	var ret []upolyFactor
+	parts, err := squareFree(p)
+	if err != nil {
+		return Rational{}, nil, err
+	}
+	for k, a := range parts {
+		if a.degree() < 1 {
+			continue
+		}
+		_, prim := a.primitive()
+		fs, err := factorSquareFree(prim)
+		if err != nil {
+			return Rational{}, nil, err
+		}
+		for _, f := range fs {
+			ret = append(ret, upolyFactor{p: f, k: k + 1})
+		}
+	}
+	// the content makes up for leading coefficients of the factors
+	prod := upoly{Integer(1)}
+	for _, f := range ret {
+		for i := 0; i < f.k; i++ {
+			prod = prod.mul(f.p)
+		}
+	}
+	content, err := p.lc().Div(prod.lc())
+	if err != nil {
+		return Rational{}, nil, err
+	}
+	if !equalUpoly(prod.scale(content), p) {
+		return Rational{}, nil, fmt.Errorf("factors found do not multiply back to the polynomial")
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return lessUpoly(ret[i].p, ret[j].p)
+	})
+	return content, ret, nil
+}
+
+func equalUpoly(p, q upoly) bool {
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		if !p[i].Equal(q[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func lessUpoly(p, q upoly) bool {
+	if p.degree() != q.degree() {
+		return p.degree() < q.degree()
+	}
+	for i := len(p) - 1; i >= 0; i-- {
+		if !p[i].Equal(q[i]) {
+			return p[i].Less(q[i])
+		}
+	}
+	return false
+}
+
+// Yun's square free decomposition, p = lc a[0] a[1]^2 a[2]^3 ... with monic
+// square free and pairwise coprime a[i]
+func squareFree(p upoly) ([]upoly, error) {
+	var ret []upoly
+	dp := p.derivative()
+	b, err := p.gcd(dp)
+	if err != nil {
+		return nil, err
+	}
+	c, _, err := p.divmod(b)
+	if err != nil {
+		return nil, err
+	}
+	d, _, err := dp.divmod(b)
+	if err != nil {
+		return nil, err
+	}
+	d = d.sub(c.derivative())
+	for c.degree() > 0 {
+		a, err := c.gcd(d)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, a)
+		if c, _, err = c.divmod(a); err != nil {
+			return nil, err
+		}
+		if d, _, err = d.divmod(a); err != nil {
+			return nil, err
+		}
+		d = d.sub(c.derivative())
+	}
+	return ret, nil
+}
+
+// Irreducible factors of a square free primitive integer polynomial, each
+// primitive with positive leading coefficient
+func factorSquareFree(p upoly) ([]upoly, error) {
+	var ret []upoly
+	roots, err := rationalRoots(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roots {
+		num, den := r.Frac()
+		// q x - p
+		linear := upoly{intRational(num).Neg(), intRational(den)}
+		if p, _, err = p.divmod(linear); err != nil {
+			return nil, err
+		}
+		ret = append(ret, linear)
+	}
+	_, p = p.primitive()
+	if p.degree() < 1 {
+		return ret, nil
+	}
+	fs, err := kronecker(p)
+	if err != nil {
+		return nil, err
+	}
+	return append(ret, fs...), nil
+}
+
+// Distinct rational roots p/q of an integer polynomial, p dividing the
+// lowest non zero coefficient and q the leading coefficient.  Only zero is
+// found when either is beyond MaxTrialDivision.
+func rationalRoots(p upoly) ([]Rational, error) {
+	var ret []Rational
+	low := 0
+	for p[low].IsZero() {
+		low++
+	}
+	if low > 0 {
+		ret = append(ret, Integer(0))
+	}
+	// nonzero roots of p / x^low
+	q := p[low:]
+	nums, ok1 := divisors(q[0])
+	dens, ok2 := divisors(q.lc())
+	if !ok1 || !ok2 {
+		return ret, nil
+	}
+	for _, num := range nums {
+		for _, den := range dens {
+			for _, sign := range []int{1, -1} {
+				r, err := NewRational(sign*num, den)
+				if err != nil {
+					return nil, err
+				}
+				if !q.eval(r).IsZero() || containsRational(ret, r) {
+					continue
+				}
+				ret = append(ret, r)
+			}
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Less(ret[j]) })
+	return ret, nil
+}
+
+func containsRational(rs []Rational, r Rational) bool {
+	for _, s := range rs {
+		if s.Equal(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Positive divisors of the integer r != 0 in increasing order, false when r
+// is not an integer or beyond MaxTrialDivision
+func divisors(r Rational) ([]int, bool) {
+	if !r.IsInt() || r.IsZero() {
+		return nil, false
+	}
+	n := r.Int()
+	if n < 0 {
+		n = -n
+	}
+	if n > MaxTrialDivision {
+		return nil, false
+	}
+	var small, large []int
+	for d := 1; d*d <= n; d++ {
+		if n%d == 0 {
+			small = append(small, d)
+			if d*d != n {
+				large = append([]int{n / d}, large...)
+			}
+		}
+	}
+	return append(small, large...), true
+}
+
+// Kronecker's method on a primitive integer polynomial without rational
+// roots, factors of degree d are interpolated through d+1 integer points at
+// which their values must divide the values of p
+func kronecker(p upoly) ([]upoly, error) {
+	for d := 2; d <= p.degree()/2; d++ {
+		g, ok, err := kroneckerFactor(p, d)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		q, _, err := p.divmod(g)
+		if err != nil {
+			return nil, err
+		}
+		_, q = q.primitive()
+		gs, err := kronecker(g)
+		if err != nil {
+			return nil, err
+		}
+		qs, err := kronecker(q)
+		if err != nil {
+			return nil, err
+		}
+		return append(gs, qs...), nil
+	}
+	return []upoly{p}, nil
+}
+
+// A factor of p of degree d, if one is found within MaxKroneckerCandidates
+func kroneckerFactor(p upoly, d int) (upoly, bool, error) {
+	// p is non zero at every integer point unless its roots were beyond
+	// MaxTrialDivision, then no divisors are tried
+	xs := make([]Rational, d+1)
+	values := make([][]int, d+1)
+	candidates := 1
+	for i := range xs {
+		// 0, 1, -1, 2, -2, ...
+		x := (i + 1) / 2
+		if i%2 == 0 {
+			x = -x
+		}
+		xs[i] = Integer(x)
+		var ok bool
+		if values[i], ok = divisors(p.eval(xs[i])); !ok {
+			return nil, false, nil
+		}
+		candidates *= 2 * len(values[i])
+		if candidates > MaxKroneckerCandidates {
+			return nil, false, nil
+		}
+	}
+	ys := make([]Rational, d+1)
+	var try func(i int) (upoly, bool, error)
+	try = func(i int) (upoly, bool, error) {
+		if i == len(xs) {
+			g, err := interpolate(xs, ys)
+			if err != nil {
+				return nil, false, err
+			}
+			if g.degree() != d || !isIntegral(g) {
+				return nil, false, nil
+			}
+			_, r, err := p.divmod(g)
+			if err != nil || len(r) > 0 {
+				return nil, false, err
+			}
+			_, g = g.primitive()
+			return g, true, nil
+		}
+		signs := []int{1, -1}
+		if i == 0 {
+			// g and -g are the same factor
+			signs = signs[:1]
+		}
+		for _, v := range values[i] {
+			for _, sign := range signs {
+				ys[i] = Integer(sign * v)
+				if g, ok, err := try(i + 1); ok || err != nil {
+					return g, ok, err
+				}
+			}
+		}
+		return nil, false, nil
+	}
+	return try(0)
+}
+
+// Lagrange interpolation through (xs[i], ys[i])
+func interpolate(xs, ys []Rational) (upoly, error) {
+	var ret upoly
+	for i := range xs {
+		basis := upoly{Integer(1)}
+		denom := Integer(1)
+		for j := range xs {
+			if j == i {
+				continue
+			}
+			basis = basis.mul(upoly{xs[j].Neg(), Integer(1)})
+			denom = denom.Mul(xs[i].Sub(xs[j]))
+		}
+		scale, err := ys[i].Div(denom)
+		if err != nil {
+			return nil, err
+		}
+		ret = ret.add(basis.scale(scale))
+	}
+	return ret, nil
+}
+
+func isIntegral(p upoly) bool {
+	for _, c := range p {
+		if !c.IsInteger() {
+			return false
+		}
+	}
+	return true
+}
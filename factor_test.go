@@ -0,0 +1,60 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestFactor(t *testing.T) {
+	cases := []struct {
+		raw, expected string
+	}{
+		{"( + ( * 2 ( ^ x 3 ) ) ( * -2 ( ^ x 1 ) ) )", "( * 2 ( + ( ^ x 1 ) -1 ) ( ^ x 1 ) ( + ( ^ x 1 ) 1 ) )"},
+		{"( + ( ^ x 2 ) ( * 2 ( ^ x 1 ) ) 1 )", "( ^ ( + ( ^ x 1 ) 1 ) 2 )"},
+		{"( + ( ^ x 2 ) -2 )", "( + ( ^ x 2 ) -2 )"},
+		{"( + ( * 1/2 ( ^ x 2 ) ) -1/8 )", "( * 1/8 ( + ( * 2 ( ^ x 1 ) ) -1 ) ( + ( * 2 ( ^ x 1 ) ) 1 ) )"},
+		{"( + ( * -1 ( ^ x 2 ) ) 1 )", "( * -1 ( + ( ^ x 1 ) -1 ) ( + ( ^ x 1 ) 1 ) )"},
+		{"( * 3 ( ^ x 4 ) )", "( * 3 ( ^ x 4 ) )"},
+		// no rational roots, found by Kronecker's method
+		{"( + ( ^ x 4 ) 4 )", "( * 1 ( + ( * -2 ( ^ x 1 ) ) ( ^ x 2 ) 2 ) ( + ( * 2 ( ^ x 1 ) ) ( ^ x 2 ) 2 ) )"},
+		{"( + ( * -1 ( ^ x 2 ) ) ( ^ x 4 ) 1 )", "( + ( * -1 ( ^ x 2 ) ) ( ^ x 4 ) 1 )"},
+		{"( * 1 ( + ( ^ x 2 ) 1 ) ( + ( ^ x 2 ) 1 ) ( + ( ^ x 1 ) -3 ) )", "( * 1 ( + ( ^ x 1 ) -3 ) ( ^ ( + ( ^ x 2 ) 1 ) 2 ) )"},
+		{"( ^ ( + ( ^ y 3 ) -1 ) 2 )", "( * 1 ( ^ ( + ( ^ y 1 ) -1 ) 2 ) ( ^ ( + ( ^ y 1 ) ( ^ y 2 ) 1 ) 2 ) )"},
+		// coefficients overflowing machine integers on the way
+		{"( + ( ^ x 12 ) ( * 7 ( ^ x 7 ) ) ( * -13 ( ^ x 3 ) ) 17 )", "( + ( * -13 ( ^ x 3 ) ) ( * 7 ( ^ x 7 ) ) ( ^ x 12 ) 17 )"},
+		{"( + ( ^ x 20 ) -1 )", "( * 1 ( + ( ^ x 1 ) -1 ) ( + ( ^ x 1 ) 1 ) ( + ( ^ x 2 ) 1 ) ( + ( * -1 ( ^ x 1 ) ) ( ^ x 2 ) ( * -1 ( ^ x 3 ) ) ( ^ x 4 ) 1 ) ( + ( ^ x 1 ) ( ^ x 2 ) ( ^ x 3 ) ( ^ x 4 ) 1 ) ( + ( * -1 ( ^ x 2 ) ) ( ^ x 4 ) ( * -1 ( ^ x 6 ) ) ( ^ x 8 ) 1 ) )"},
+		// divisors beyond MaxTrialDivision are not searched
+		{"( + ( ^ x 2 ) -1000000000000000000000000000000 )", "( + ( ^ x 2 ) -1000000000000000000000000000000 )"},
+		{"( * 1 ( + ( ^ x 1 ) 1 ) ( + ( ^ x 1 ) -10000000000000000000000 ) )", "( + ( * -9999999999999999999999 ( ^ x 1 ) ) ( ^ x 2 ) -10000000000000000000000 )"},
+		{"6", "6"},
+		{"( + ( ^ x 1 ) ( * -1 ( ^ x 1 ) ) )", "0"},
+	}
+	for _, c := range cases {
+		poly := polyFromString(t, c.raw)
+		f, err := Factor(poly)
+		require.NoError(t, err, c.raw)
+		assert.Equal(t, c.expected, f.ToSExp().String(), c.raw)
+		// factoring is exact
+		eq, err := Equivalent(poly, *f)
+		require.NoError(t, err)
+		assert.True(t, eq.Equivalent && eq.Exact, c.raw)
+	}
+}
+
+func TestFactorInvalid(t *testing.T) {
+	for _, raw := range []string{
+		"( + ( ^ x 2 ) ( ^ y 1 ) )",
+		"( + ( ^ x 2 ) a )",
+		"( + ( ^ x -1 ) 1 )",
+		"( abs ( ^ x 1 ) )",
+		// degrees are bounded before allocating coefficients
+		"( ^ x 10000000000 )",
+		"( + ( ^ x 65537 ) 1 )",
+	} {
+		_, err := Factor(polyFromString(t, raw))
+		assert.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,223 @@
+package symdiff
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+/*
+   Structural hashing and hash-consing
+
+   Hash is stable across runs and processes: structurally equal expressions,
+   i.e. those that print the same, hash the same.  A node's hash is computed
+   from its label (kind, symbol, rational exponent ...) and the hashes of its
+   children, see Walk.
+
+   An Arena interns expressions so that equal subtrees are represented by one
+   shared node.  Interned nodes can then be compared and memoized by identity,
+   e.g. keyed on Expr(), instead of structurally.
+
+     arena := NewArena()
+     jacobian[i][j] = arena.Intern(d)
+*/
+
+// Structural hash of the expression
+func (p *PolyExp) Hash() uint64 {
+	return p.e.Hash()
+}
+
+// Combine a node label with the hashes of its children
+func hashNode(label []string, children []uint64) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, l := range label {
+		h.Write([]byte(l))
+		// separator so ["ab", "c"] and ["a", "bc"] differ
+		h.Write([]byte{0})
+	}
+	for _, c := range children {
+		binary.LittleEndian.PutUint64(buf[:], c)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+func hashExpr(e Expr) uint64 {
+	children := e.children()
+	hashes := make([]uint64, len(children))
+	for i := range children {
+		hashes[i] = children[i].Hash()
+	}
+	return hashNode(e.label(), hashes)
+}
+
+func (c *ConstantExp) Hash() uint64 {
+	return hashExpr(c)
+}
+
+func (a *ParamExp) Hash() uint64 {
+	return hashExpr(a)
+}
+
+func (m *MonomialExp) Hash() uint64 {
+	return hashExpr(m)
+}
+
+func (p *ProductExp) Hash() uint64 {
+	return hashExpr(p)
+}
+
+func (pow *PowerExp) Hash() uint64 {
+	return hashExpr(pow)
+}
+
+func (sum *SumExp) Hash() uint64 {
+	return hashExpr(sum)
+}
+
+func (a *AbsExp) Hash() uint64 {
+	return hashExpr(a)
+}
+
+func (sgn *SignExp) Hash() uint64 {
+	return hashExpr(sgn)
+}
+
+func (pw *PiecewiseExp) Hash() uint64 {
+	return hashExpr(pw)
+}
+
+func (c *ConstantExp) label() []string {
+	return []string{"const", c.c.String()}
+}
+
+func (a *ParamExp) label() []string {
+	return []string{"param", string(a.a)}
+}
+
+func (m *MonomialExp) label() []string {
+	if m.e != nil {
+		return []string{"mon", string(m.x)}
+	}
+	return []string{"mon", string(m.x), m.n.String()}
+}
+
+func (p *ProductExp) label() []string {
+	return []string{"*"}
+}
+
+func (pow *PowerExp) label() []string {
+	return []string{"^", pow.n.String()}
+}
+
+func (sum *SumExp) label() []string {
+	return []string{"+"}
+}
+
+func (a *AbsExp) label() []string {
+	return []string{"abs"}
+}
+
+func (sgn *SignExp) label() []string {
+	return []string{"sign"}
+}
+
+// Comparisons in order, they determine how children split into conditions
+// and pieces
+func (pw *PiecewiseExp) label() []string {
+	ret := make([]string, 0, len(pw.cs)+1)
+	ret = append(ret, "piecewise")
+	for _, c := range pw.cs {
+		ret = append(ret, c.op)
+	}
+	return ret
+}
+
+// Hash-consing arena sharing equal subtrees between interned expressions.
+// Interned expressions must not be modified, which holds for expressions
+// built by this package.  Not safe for concurrent use.
+type Arena struct {
+	// interned nodes by hash, more than one on collisions
+	buckets map[uint64][]Expr
+	// hashes of interned nodes
+	hashes map[Expr]uint64
+}
+
+func NewArena() *Arena {
+	return &Arena{
+		buckets: make(map[uint64][]Expr),
+		hashes:  make(map[Expr]uint64),
+	}
+}
+
+// Number of unique nodes interned
+func (a *Arena) Len() int {
+	return len(a.hashes)
+}
+
+// Expression equal to exp built from interned nodes, nodes seen before are
+// reused and new ones are added to the arena
+func (a *Arena) Intern(exp PolyExp) (PolyExp, error) {
+	if err := exp.check(); err != nil {
+		return PolyExp{}, err
+	}
+	e, err := a.intern(exp.e)
+	if err != nil {
+		return PolyExp{}, err
+	}
+	return PolyExp{e: e}, nil
+}
+
+func (a *Arena) intern(e Expr) (Expr, error) {
+	if _, ok := a.hashes[e]; ok {
+		return e, nil
+	}
+	children := e.children()
+	hashes := make([]uint64, len(children))
+	changed := false
+	for i := range children {
+		c, err := a.intern(children[i].e)
+		if err != nil {
+			return nil, err
+		}
+		changed = changed || c != children[i].e
+		children[i] = PolyExp{e: c}
+		hashes[i] = a.hashes[c]
+	}
+	label := e.label()
+	h := hashNode(label, hashes)
+	for _, candidate := range a.buckets[h] {
+		if sameNode(candidate, label, children) {
+			return candidate, nil
+		}
+	}
+	if changed {
+		rebuilt, err := e.rebuild(children)
+		if err != nil {
+			return nil, err
+		}
+		e = rebuilt
+	}
+	a.buckets[h] = append(a.buckets[h], e)
+	a.hashes[e] = h
+	return e, nil
+}
+
+// Interned node with the given label and interned children
+func sameNode(e Expr, label []string, children []PolyExp) bool {
+	l, cs := e.label(), e.children()
+	if len(l) != len(label) || len(cs) != len(children) {
+		return false
+	}
+	for i := range l {
+		if l[i] != label[i] {
+			return false
+		}
+	}
+	for i := range cs {
+		if cs[i].e != children[i].e {
+			return false
+		}
+	}
+	return true
+}
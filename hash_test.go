@@ -0,0 +1,83 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestHash(t *testing.T) {
+	raw := "( + ( * a ( ^ x 2 ) ( abs ( ^ y b ) ) ) ( ^ ( + ( ^ x 1 ) 1 ) -1/2 ) " + hinge + " 3 )"
+	p := polyFromString(t, raw, "a", "b")
+	q := polyFromString(t, raw, "a", "b")
+	assert.Equal(t, p.Hash(), q.Hash())
+	c := p.Clone()
+	assert.Equal(t, p.Hash(), c.Hash())
+	// stable across runs
+	r := polyFromString(t, "( + ( ^ x 2 ) 1 )")
+	assert.Equal(t, uint64(0xe5a548262c5b0187), r.Hash())
+
+	distinct := []string{
+		"( ^ x 2 )",
+		"( ^ y 2 )",
+		"( ^ x 1/2 )",
+		"( ^ x a )",
+		"x",
+		"2",
+		"( + ( ^ x 2 ) 1 )",
+		"( + 1 ( ^ x 2 ) )",
+		"( * 1 ( ^ x 2 ) ( ^ y 1 ) )",
+		"( * 1 ( ^ y 1 ) ( ^ x 2 ) )",
+		"( abs ( ^ x 2 ) )",
+		"( sign ( ^ x 2 ) )",
+		"( piecewise ( ( < ( ^ x 1 ) 0 ) 1 ) ( else 2 ) )",
+		"( piecewise ( ( > ( ^ x 1 ) 0 ) 1 ) ( else 2 ) )",
+	}
+	seen := make(map[uint64]string)
+	for _, raw := range distinct {
+		poly := polyFromString(t, raw, "a")
+		h := poly.Hash()
+		other, ok := seen[h]
+		assert.False(t, ok, "%s and %s collide", raw, other)
+		seen[h] = raw
+	}
+}
+
+func TestArena(t *testing.T) {
+	arena := NewArena()
+	sub := "( ^ ( + ( * a ( ^ x 2 ) ) 1 ) -1 )"
+	p, err := arena.Intern(polyFromString(t, "( + "+sub+" ( * 2 "+sub+" ) )", "a"))
+	require.NoError(t, err)
+	q, err := arena.Intern(polyFromString(t, "( * 3 "+sub+" )", "a"))
+	require.NoError(t, err)
+	// interning keeps the expression
+	assert.Equal(t, "( + "+sub+" ( * 2 "+sub+" ) )", p.ToSExp().String())
+
+	// ( ^ _ -1 ) ( + _ 1 ) ( * a _ ) a ( ^ x 2 ) 1 + 2, ( * 2 _ ), ( + _ _ ), 3, ( * 3 _ )
+	assert.Equal(t, 11, arena.Len())
+
+	// equal subtrees are the same node
+	var subs []Expr
+	for _, poly := range []PolyExp{p, q} {
+		Inspect(poly, func(e PolyExp) bool {
+			if e.IsPower() {
+				subs = append(subs, e.Expr())
+			}
+			return true
+		})
+	}
+	require.Len(t, subs, 3)
+	assert.True(t, subs[0] == subs[1])
+	assert.True(t, subs[0] == subs[2])
+
+	// interning again returns the same root
+	again, err := arena.Intern(polyFromString(t, "( * 3 "+sub+" )", "a"))
+	require.NoError(t, err)
+	assert.True(t, again.Expr() == q.Expr())
+	assert.Equal(t, 11, arena.Len())
+
+	_, err = arena.Intern(PolyExp{})
+	assert.Error(t, err)
+}
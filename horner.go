@@ -0,0 +1,107 @@
+package symdiff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+   Horner form
+
+   A polynomial c0 + c1 x + ... + cn x^n in one variable is nested as
+   ( ... ( cn x + cn-1 ) x + ... ) x + c0, n multiplications and additions
+   instead of the powers of the expanded form.  Runs of zero coefficients
+   multiply by a power of the variable.
+
+     ( + ( * 3 ( ^ x 2 ) ) ( * 2 ( ^ x 1 ) ) 1 )
+       ==> ( + ( * 1 ( + ( * 3 ( ^ x 1 ) ) 2 ) ( ^ x 1 ) ) 1 )
+       ==> (3*x + 2)*x + 1
+
+   HornerString writes the nesting in infix for code generation, integer
+   coefficients as integers and other rationals as floating point literals.
+*/
+
+// Nested Horner form of a polynomial in v with rational coefficients
+func Horner(poly PolyExp, v Symbol) (*PolyExp, error) {
+	p, err := toUnivariateIn(poly, v)
+	if err != nil {
+		return nil, fmt.Errorf("%s, can only write polynomials in %s with rational coefficients in Horner form", err, v)
+	}
+	if p.degree() < 1 {
+		ret := p.toPolyExp(v)
+		return &ret, nil
+	}
+	ret := PolyExp{e: &ConstantExp{c: p.lc()}}
+	for k, j := p.degree()-1, 1; k >= 0; k-- {
+		if p[k].IsZero() && k > 0 {
+			j++
+			continue
+		}
+		x := PolyExp{e: &MonomialExp{x: v, n: Integer(j)}}
+		switch {
+		case ret.IsConstant() && ret.constant().c.Equal(Integer(1)):
+			ret = x
+		case ret.IsConstant():
+			ret = PolyExp{e: &ProductExp{l: &PolyExp{e: ret.e}, r: &x}}
+		default:
+			inner := ret
+			ret = PolyExp{e: &ProductExp{l: &PolyExp{e: &ConstantExp{c: Integer(1)}}, r: &inner, fs: []PolyExp{x}}}
+		}
+		if !p[k].IsZero() {
+			ret = *Join([]PolyExp{ret, {e: &ConstantExp{c: p[k]}}})
+		}
+		j = 1
+	}
+	return &ret, nil
+}
+
+// Horner form of a polynomial in v in infix, e.g. (3*x + 2)*x + 1
+func HornerString(poly PolyExp, v Symbol) (string, error) {
+	p, err := toUnivariateIn(poly, v)
+	if err != nil {
+		return "", fmt.Errorf("%s, can only write polynomials in %s with rational coefficients in Horner form", err, v)
+	}
+	if p.degree() < 1 {
+		return hornerCoefficient(p.lc()), nil
+	}
+	var b strings.Builder
+	// the leading coefficient is written on its own until the first addition
+	single := true
+	b.WriteString(hornerCoefficient(p.lc()))
+	for k, j := p.degree()-1, 1; k >= 0; k-- {
+		if p[k].IsZero() && k > 0 {
+			j++
+			continue
+		}
+		h := b.String()
+		b.Reset()
+		switch {
+		case single && p.lc().Equal(Integer(1)):
+			b.WriteString(string(v))
+		case single && p.lc().Equal(Integer(-1)):
+			b.WriteString("-" + string(v))
+		case single:
+			b.WriteString(h + "*" + string(v))
+		default:
+			b.WriteString("(" + h + ")*" + string(v))
+		}
+		b.WriteString(strings.Repeat("*"+string(v), j-1))
+		switch {
+		case p[k].Sign() > 0:
+			b.WriteString(" + " + hornerCoefficient(p[k]))
+		case p[k].Sign() < 0:
+			b.WriteString(" - " + hornerCoefficient(p[k].Neg()))
+		}
+		single = p[k].IsZero() && single
+		j = 1
+	}
+	return b.String(), nil
+}
+
+func hornerCoefficient(c Rational) string {
+	if c.IsInteger() {
+		return c.String()
+	}
+	return strconv.FormatFloat(c.Float64(), 'g', -1, 64)
+}
@@ -0,0 +1,43 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestHorner(t *testing.T) {
+	cases := []struct {
+		raw, expected, infix string
+	}{
+		{"( + ( * 3 ( ^ x 2 ) ) ( * 2 ( ^ x 1 ) ) 1 )", "( + ( * 1 ( + ( * 3 ( ^ x 1 ) ) 2 ) ( ^ x 1 ) ) 1 )", "(3*x + 2)*x + 1"},
+		{"( + ( ^ x 3 ) ( ^ x 2 ) )", "( * 1 ( + ( ^ x 1 ) 1 ) ( ^ x 2 ) )", "(x + 1)*x*x"},
+		{"( + ( * -1 ( ^ x 4 ) ) ( * 1/2 ( ^ x 1 ) ) -7 )", "( + ( * 1 ( + ( * -1 ( ^ x 3 ) ) 1/2 ) ( ^ x 1 ) ) -7 )", "(-x*x*x + 0.5)*x - 7"},
+		{"( * 1 ( + ( ^ x 1 ) 1 ) ( + ( ^ x 1 ) -1 ) )", "( + ( ^ x 2 ) -1 )", "x*x - 1"},
+		{"( * 2 ( ^ x 1 ) )", "( * 2 ( ^ x 1 ) )", "2*x"},
+		{"( ^ x 1 )", "( ^ x 1 )", "x"},
+		{"5", "5", "5"},
+		{"0", "0", "0"},
+	}
+	for _, c := range cases {
+		poly := polyFromString(t, c.raw)
+		h, err := Horner(poly, "x")
+		require.NoError(t, err, c.raw)
+		assert.Equal(t, c.expected, h.ToSExp().String(), c.raw)
+		eq, err := Equivalent(poly, *h)
+		require.NoError(t, err)
+		assert.True(t, eq.Equivalent && eq.Exact, c.raw)
+		infix, err := HornerString(poly, "x")
+		require.NoError(t, err, c.raw)
+		assert.Equal(t, c.infix, infix, c.raw)
+	}
+
+	_, err := Horner(polyFromString(t, "( + ( ^ x 2 ) ( ^ y 1 ) )"), "x")
+	assert.Error(t, err)
+	_, err = HornerString(polyFromString(t, "( abs ( ^ x 1 ) )"), "x")
+	assert.Error(t, err)
+	_, err = Horner(polyFromString(t, "( ^ x 10000000000 )"), "x")
+	assert.Error(t, err)
+}
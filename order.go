@@ -0,0 +1,148 @@
+package symdiff
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+   Term orderings
+
+   Fold orders the monomials of a sum by one of the following, symbols
+   compare alphabetically with a > b > ... > z in the textbook orderings.
+
+   - Ascending: grouped by symbol, numeric powers ascending, the default
+       ( + ( ^ x 1 ) ( ^ x 2 ) ( ^ y 1 ) 1 )
+   - Descending: grouped by symbol, numeric powers descending
+       ( + ( ^ x 2 ) ( ^ x 1 ) ( ^ y 1 ) 1 )
+   - Lex: decreasing lexicographic order of the exponents
+       ( + ( ^ x 2 ) ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( ^ y 3 ) 1 )
+   - GradedLex: decreasing total degree, ties decreasing lexicographically
+       ( + ( ^ y 3 ) ( ^ x 2 ) ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) 1 )
+   - GradedReverseLex: decreasing total degree, ties by the smaller exponent
+     of the last symbol in which they differ
+       ( + ( * 1 ( ^ x 2 ) ( ^ y 1 ) ) ( * 1 ( ^ x 1 ) ( ^ z 2 ) ) )
+
+   The textbook orderings also place products of powers of several symbols,
+   which Fold keeps as they are, among the monomials.  Monomials with
+   symbolic exponents follow, then powers of expressions and other compound
+   terms, parameters and the constant last.
+*/
+
+// Order of the terms of a sum produced by Fold
+type TermOrder int
+
+const (
+	// Grouped by symbol, numeric powers ascending
+	Ascending TermOrder = iota
+	// Grouped by symbol, numeric powers descending
+	Descending
+	// Decreasing lexicographic order of exponents
+	Lex
+	// Decreasing total degree then lexicographic
+	GradedLex
+	// Decreasing total degree then reverse lexicographic
+	GradedReverseLex
+)
+
+var termOrderNames = map[TermOrder]string{
+	Ascending:        "ascending",
+	Descending:       "descending",
+	Lex:              "lex",
+	GradedLex:        "grlex",
+	GradedReverseLex: "grevlex",
+}
+
+func (o TermOrder) String() string {
+	return termOrderNames[o]
+}
+
+func ParseTermOrder(raw string) (TermOrder, error) {
+	for o, name := range termOrderNames {
+		if name == raw {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid term order %s, expected one of ascending, descending, lex, grlex or grevlex", raw)
+}
+
+// Orders comparing exponents of all symbols together rather than per symbol
+func (o TermOrder) monomial() bool {
+	return o == Lex || o == GradedLex || o == GradedReverseLex
+}
+
+// Exponents of a power of a symbol or a product of such powers with a
+// coefficient, false for other terms
+func termDegrees(t PolyExp) (map[Symbol]Rational, bool) {
+	ret := make(map[Symbol]Rational)
+	var factors []PolyExp
+	switch {
+	case t.IsMon():
+		factors = []PolyExp{t}
+	case t.IsProduct():
+		l, fs := t.product().Term()
+		if !l.isCoefficient() {
+			return nil, false
+		}
+		factors = fs
+	default:
+		return nil, false
+	}
+	for _, f := range factors {
+		if !f.IsMon() || f.mon().e != nil {
+			return nil, false
+		}
+		m := f.mon()
+		if n, ok := ret[m.x]; ok {
+			ret[m.x] = n.Add(m.n)
+			continue
+		}
+		ret[m.x] = m.n
+	}
+	return ret, true
+}
+
+// Whether exponents a come before b in a textbook order
+func (o TermOrder) less(a, b map[Symbol]Rational) bool {
+	syms := make([]string, 0, len(a)+len(b))
+	for x := range a {
+		syms = append(syms, string(x))
+	}
+	for x := range b {
+		if _, ok := a[x]; !ok {
+			syms = append(syms, string(x))
+		}
+	}
+	sort.Strings(syms)
+	// exponent differences a - b
+	diff := make([]Rational, len(syms))
+	total := Integer(0)
+	for i, x := range syms {
+		diff[i] = exponentOf(a, Symbol(x)).Sub(exponentOf(b, Symbol(x)))
+		total = total.Add(diff[i])
+	}
+	if o != Lex && !total.IsZero() {
+		return total.Sign() > 0
+	}
+	if o == GradedReverseLex {
+		for i := len(diff) - 1; i >= 0; i-- {
+			if !diff[i].IsZero() {
+				return diff[i].Sign() < 0
+			}
+		}
+		return false
+	}
+	for _, d := range diff {
+		if !d.IsZero() {
+			return d.Sign() > 0
+		}
+	}
+	return false
+}
+
+func exponentOf(degrees map[Symbol]Rational, x Symbol) Rational {
+	if n, ok := degrees[x]; ok {
+		return n
+	}
+	return Integer(0)
+}
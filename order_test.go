@@ -0,0 +1,34 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestFoldOrder(t *testing.T) {
+	poly := polyFromString(t, "( + 1 ( ^ y 3 ) ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( ^ x 1 ) ( ^ x 2 ) ( * 1 ( ^ x 2 ) ( ^ y 1 ) ) ( * 1 ( ^ x 1 ) ( ^ z 2 ) ) ( ^ x n ) )", "n")
+	cases := []struct {
+		order    TermOrder
+		expected string
+	}{
+		{Ascending, "( + ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( * 1 ( ^ x 2 ) ( ^ y 1 ) ) ( * 1 ( ^ x 1 ) ( ^ z 2 ) ) ( ^ x 1 ) ( ^ x 2 ) ( ^ x n ) ( ^ y 3 ) 1 )"},
+		{Descending, "( + ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( * 1 ( ^ x 2 ) ( ^ y 1 ) ) ( * 1 ( ^ x 1 ) ( ^ z 2 ) ) ( ^ x 2 ) ( ^ x 1 ) ( ^ x n ) ( ^ y 3 ) 1 )"},
+		{Lex, "( + ( * 1 ( ^ x 2 ) ( ^ y 1 ) ) ( ^ x 2 ) ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( * 1 ( ^ x 1 ) ( ^ z 2 ) ) ( ^ x 1 ) ( ^ y 3 ) ( ^ x n ) 1 )"},
+		{GradedLex, "( + ( * 1 ( ^ x 2 ) ( ^ y 1 ) ) ( * 1 ( ^ x 1 ) ( ^ z 2 ) ) ( ^ y 3 ) ( ^ x 2 ) ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( ^ x 1 ) ( ^ x n ) 1 )"},
+		{GradedReverseLex, "( + ( * 1 ( ^ x 2 ) ( ^ y 1 ) ) ( ^ y 3 ) ( * 1 ( ^ x 1 ) ( ^ z 2 ) ) ( ^ x 2 ) ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( ^ x 1 ) ( ^ x n ) 1 )"},
+	}
+	for _, c := range cases {
+		folded := Join(FoldOrder(Flatten(poly), c.order))
+		assert.Equal(t, c.expected, folded.ToSExp().String(), c.order.String())
+
+		parsed, err := ParseTermOrder(c.order.String())
+		require.NoError(t, err)
+		assert.Equal(t, c.order, parsed)
+	}
+	assert.Equal(t, Join(Fold(Flatten(poly))).ToSExp().String(), cases[0].expected)
+	_, err := ParseTermOrder("alphabetical")
+	assert.Error(t, err)
+}
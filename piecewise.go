@@ -0,0 +1,320 @@
+package symdiff
+
+import (
+	"fmt"
+)
+
+/*
+   Non polynomial expressions
+
+   <abs exp> ::= ( abs <poly exp> )
+   <sign exp> ::= ( sign <poly exp> )
+   <piecewise exp> ::= ( piecewise ( <condition> <poly exp> ) ... ( <condition> <poly exp> ) )
+   <condition> ::= ( <comparison> <poly exp> <poly exp> ) | else
+   <comparison> ::= < | <= | > | >= | =
+
+   A piecewise expression takes the value of the first piece whose condition holds
+   and is undefined where no condition holds.
+
+   Examples
+
+   L1 loss |x - y|             ( abs ( + ( ^ x 1 ) ( * -1 ( ^ y 1 ) ) ) )
+   hinge loss max(0, 1 - x)    ( piecewise ( ( < ( ^ x 1 ) 1 ) ( + 1 ( * -1 ( ^ x 1 ) ) ) ) ( else 0 ) )
+*/
+
+const AbsKeyWord = "abs"
+const SignKeyWord = "sign"
+const PiecewiseKeyWord = "piecewise"
+const ElseKeyWord = "else"
+
+const LessKeyWord = "<"
+const LessEqualKeyWord = "<="
+const GreaterKeyWord = ">"
+const GreaterEqualKeyWord = ">="
+const EqualKeyWord = "="
+
+func init() {
+	for _, cmp := range []string{LessKeyWord, LessEqualKeyWord, GreaterKeyWord, GreaterEqualKeyWord, EqualKeyWord} {
+		SpecialAtoms[cmp] = struct{}{}
+	}
+}
+
+func matchKeyWord(sexp SExp, keyword string) bool {
+	return sexp.Atom != nil && *sexp.Atom == Atom(keyword)
+}
+
+// Parse ( <keyword> <poly exp> ) into the inner expression
+func parseUnary(sexp SExp, keyword string, params map[Symbol]bool) (*PolyExp, error) {
+	if len(sexp.List) != 2 || !matchKeyWord(sexp.List[0], keyword) {
+		return nil, fmt.Errorf("invalid SExp, cannot parse as %s %s", keyword, sexp.String())
+	}
+	var e PolyExp
+	if err := e.parse(sexp.List[1], params); err != nil {
+		return nil, fmt.Errorf("%s, failed to parse argument of %s %s", err, keyword, sexp.String())
+	}
+	return &e, nil
+}
+
+type AbsExp struct {
+	e *PolyExp
+}
+
+// Getter for argument of absolute value
+// Fields are private to restrict setting to parsing
+func (a *AbsExp) Term() PolyExp {
+	return *a.e
+}
+
+func (a *AbsExp) match(sexp SExp) bool {
+	return matchKeyWord(sexp, AbsKeyWord)
+}
+
+func (a *AbsExp) ToSExp() SExp {
+	return SExp{
+		List: []SExp{
+			NewAtom(AbsKeyWord),
+			a.e.ToSExp(),
+		},
+	}
+}
+
+func (a *AbsExp) Parse(sexp SExp) error {
+	return a.parse(sexp, nil)
+}
+
+func (a *AbsExp) parse(sexp SExp, params map[Symbol]bool) error {
+	e, err := parseUnary(sexp, AbsKeyWord, params)
+	if err != nil {
+		return err
+	}
+	a.e = e
+	return nil
+}
+
+type SignExp struct {
+	e *PolyExp
+}
+
+// Getter for argument of sign
+// Fields are private to restrict setting to parsing
+func (sgn *SignExp) Term() PolyExp {
+	return *sgn.e
+}
+
+func (sgn *SignExp) match(sexp SExp) bool {
+	return matchKeyWord(sexp, SignKeyWord)
+}
+
+func (sgn *SignExp) ToSExp() SExp {
+	return SExp{
+		List: []SExp{
+			NewAtom(SignKeyWord),
+			sgn.e.ToSExp(),
+		},
+	}
+}
+
+func (sgn *SignExp) Parse(sexp SExp) error {
+	return sgn.parse(sexp, nil)
+}
+
+func (sgn *SignExp) parse(sexp SExp, params map[Symbol]bool) error {
+	e, err := parseUnary(sexp, SignKeyWord, params)
+	if err != nil {
+		return err
+	}
+	sgn.e = e
+	return nil
+}
+
+func (a *AbsExp) clone() Expr {
+	e := a.e.Clone()
+	return &AbsExp{e: &e}
+}
+
+func (a *AbsExp) mentions(v Symbol) bool {
+	return a.e.mentions(v)
+}
+
+func (a *AbsExp) isCoefficient() bool {
+	return false
+}
+
+func (sgn *SignExp) clone() Expr {
+	e := sgn.e.Clone()
+	return &SignExp{e: &e}
+}
+
+func (sgn *SignExp) mentions(v Symbol) bool {
+	return sgn.e.mentions(v)
+}
+
+func (sgn *SignExp) isCoefficient() bool {
+	return false
+}
+
+// Comparison of two expressions guarding a piece, the else condition always holds
+type Condition struct {
+	// one of the comparison keywords or else
+	op string
+	l  *PolyExp
+	r  *PolyExp
+}
+
+// Getter for comparison and compared expressions, only the comparison is set for else
+// Fields are private to restrict setting to parsing
+func (c *Condition) Term() (string, PolyExp, PolyExp) {
+	if c.IsElse() {
+		return c.op, PolyExp{}, PolyExp{}
+	}
+	return c.op, *c.l, *c.r
+}
+
+func (c *Condition) IsElse() bool {
+	return c.op == ElseKeyWord
+}
+
+func (c *Condition) ToSExp() SExp {
+	if c.IsElse() {
+		return NewAtom(ElseKeyWord)
+	}
+	return SExp{
+		List: []SExp{
+			NewAtom(c.op),
+			c.l.ToSExp(),
+			c.r.ToSExp(),
+		},
+	}
+}
+
+func (c *Condition) Parse(sexp SExp) error {
+	return c.parse(sexp, nil)
+}
+
+func (c *Condition) parse(sexp SExp, params map[Symbol]bool) error {
+	if matchKeyWord(sexp, ElseKeyWord) {
+		c.op = ElseKeyWord
+		return nil
+	}
+	if len(sexp.List) != 3 || sexp.List[0].Atom == nil {
+		return fmt.Errorf("invalid SExp, cannot parse as condition %s", sexp.String())
+	}
+	switch op := string(*sexp.List[0].Atom); op {
+	case LessKeyWord, LessEqualKeyWord, GreaterKeyWord, GreaterEqualKeyWord, EqualKeyWord:
+		c.op = op
+	default:
+		return fmt.Errorf("invalid comparison %s in condition %s", op, sexp.String())
+	}
+	var l, r PolyExp
+	if err := l.parse(sexp.List[1], params); err != nil {
+		return fmt.Errorf("%s, failed to parse left side of condition %s", err, sexp.String())
+	}
+	if err := r.parse(sexp.List[2], params); err != nil {
+		return fmt.Errorf("%s, failed to parse right side of condition %s", err, sexp.String())
+	}
+	c.l, c.r = &l, &r
+	return nil
+}
+
+// Report whether the condition holds given evaluated sides
+func (c *Condition) holds(l, r float64) bool {
+	switch c.op {
+	case LessKeyWord:
+		return l < r
+	case LessEqualKeyWord:
+		return l <= r
+	case GreaterKeyWord:
+		return l > r
+	case GreaterEqualKeyWord:
+		return l >= r
+	case EqualKeyWord:
+		return l == r
+	}
+	return true
+}
+
+type PiecewiseExp struct {
+	// Invariant: len(cs) == len(es) > 0
+	cs []Condition
+	es []PolyExp
+}
+
+// Getter for conditions and their pieces
+// Fields are private to restrict setting to parsing
+func (pw *PiecewiseExp) Term() ([]Condition, []PolyExp) {
+	return append([]Condition{}, pw.cs...), append([]PolyExp{}, pw.es...)
+}
+
+func (pw *PiecewiseExp) match(sexp SExp) bool {
+	return matchKeyWord(sexp, PiecewiseKeyWord)
+}
+
+func (pw *PiecewiseExp) ToSExp() SExp {
+	sub := []SExp{NewAtom(PiecewiseKeyWord)}
+	for i := range pw.cs {
+		sub = append(sub, SExp{
+			List: []SExp{
+				pw.cs[i].ToSExp(),
+				pw.es[i].ToSExp(),
+			},
+		})
+	}
+	return SExp{
+		List: sub,
+	}
+}
+
+func (pw *PiecewiseExp) Parse(sexp SExp) error {
+	return pw.parse(sexp, nil)
+}
+
+func (pw *PiecewiseExp) parse(sexp SExp, params map[Symbol]bool) error {
+	if len(sexp.List) < 2 || !pw.match(sexp.List[0]) {
+		return fmt.Errorf("invalid SExp, cannot parse as piecewise %s", sexp.String())
+	}
+	for _, piece := range sexp.List[1:] {
+		if len(piece.List) != 2 {
+			return fmt.Errorf("invalid piece %s, expected ( <condition> <poly exp> ) in piecewise %s", piece.String(), sexp.String())
+		}
+		var c Condition
+		if err := c.parse(piece.List[0], params); err != nil {
+			return fmt.Errorf("%s, failed to parse condition of piece %s", err, piece.String())
+		}
+		var e PolyExp
+		if err := e.parse(piece.List[1], params); err != nil {
+			return fmt.Errorf("%s, failed to parse expression of piece %s", err, piece.String())
+		}
+		pw.cs = append(pw.cs, c)
+		pw.es = append(pw.es, e)
+	}
+	return nil
+}
+
+func (pw *PiecewiseExp) clone() Expr {
+	ret := PiecewiseExp{cs: make([]Condition, len(pw.cs)), es: cloneAll(pw.es)}
+	for i, c := range pw.cs {
+		if !c.IsElse() {
+			l, r := c.l.Clone(), c.r.Clone()
+			c.l, c.r = &l, &r
+		}
+		ret.cs[i] = c
+	}
+	return &ret
+}
+
+func (pw *PiecewiseExp) mentions(v Symbol) bool {
+	for i := range pw.cs {
+		c := pw.cs[i]
+		if !c.IsElse() && (c.l.mentions(v) || c.r.mentions(v)) {
+			return true
+		}
+		if pw.es[i].mentions(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (pw *PiecewiseExp) isCoefficient() bool {
+	return false
+}
@@ -0,0 +1,99 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+const hinge = "( piecewise ( ( < ( ^ x 1 ) 1 ) ( + 1 ( * -1 ( ^ x 1 ) ) ) ) ( else 0 ) )"
+
+func TestParsePiecewise(t *testing.T) {
+	poly := polyFromString(t, "( + ( abs ( ^ x 1 ) ) ( sign a ) )", "a")
+	assert.Equal(t, "( + ( abs ( ^ x 1 ) ) ( sign a ) )", poly.ToSExp().String())
+
+	poly = polyFromString(t, hinge)
+	require.True(t, poly.IsPiecewise())
+	pw, err := poly.Piecewise()
+	require.NoError(t, err)
+	cs, es := pw.Term()
+	require.Len(t, cs, 2)
+	require.Len(t, es, 2)
+	assert.False(t, cs[0].IsElse())
+	assert.True(t, cs[1].IsElse())
+	assert.Equal(t, hinge, poly.ToSExp().String())
+
+	for _, bad := range []string{
+		"( abs ( ^ x 1 ) 2 )",
+		"( piecewise )",
+		"( piecewise ( ( ^ x 1 ) 0 ) )",
+		"( piecewise ( ( < ( ^ x 1 ) ) 0 ) )",
+		"( piecewise ( else ) )",
+	} {
+		var sexp SExp
+		require.NoError(t, sexp.Parse(bad))
+		var poly PolyExp
+		assert.Error(t, poly.Parse(sexp), bad)
+	}
+}
+
+func TestDiffPiecewise(t *testing.T) {
+	derivative, err := Differentiate("x", polyFromString(t, "( abs ( + ( ^ x 2 ) -1 ) )"))
+	require.NoError(t, err)
+	s, err := Simplify(*derivative)
+	require.NoError(t, err)
+	assert.Equal(t, "( piecewise ( ( < ( + ( ^ x 2 ) -1 ) 0 ) ( * -2 ( ^ x 1 ) ) ) ( ( > ( + ( ^ x 2 ) -1 ) 0 ) ( * 2 ( ^ x 1 ) ) ) )", s.ToSExp().String())
+
+	derivative, err = Differentiate("x", polyFromString(t, "( sign ( ^ x 1 ) )"))
+	require.NoError(t, err)
+	assert.Equal(t, "( piecewise ( ( < ( ^ x 1 ) 0 ) 0 ) ( ( > ( ^ x 1 ) 0 ) 0 ) )", derivative.ToSExp().String())
+
+	derivative, err = Differentiate("x", polyFromString(t, hinge))
+	require.NoError(t, err)
+	s, err = Simplify(*derivative)
+	require.NoError(t, err)
+	assert.Equal(t, "( piecewise ( ( < ( ^ x 1 ) 1 ) -1 ) ( else 0 ) )", s.ToSExp().String())
+
+	// abs and sign are undefined at 0
+	derivative, err = Differentiate("x", polyFromString(t, "( + ( abs ( ^ x 1 ) ) ( sign ( ^ x 1 ) ) )"))
+	require.NoError(t, err)
+	_, err = Evaluate(*derivative, map[Symbol]float64{"x": 0})
+	assert.Error(t, err)
+	v, err := Evaluate(*derivative, map[Symbol]float64{"x": -2})
+	assert.NoError(t, err)
+	assert.Equal(t, -1.0, v)
+}
+
+func TestSimplifyPiecewise(t *testing.T) {
+	poly := polyFromString(t, "( + ( abs ( ^ x 1 ) ) ( * 2 ( abs ( + ( ^ x 1 ) 0 ) ) ) ( abs -3 ) ( sign -2 ) )")
+	s, err := Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 3 ( abs ( ^ x 1 ) ) ) 2 )", s.ToSExp().String())
+
+	// decidable conditions select their piece
+	poly = polyFromString(t, "( piecewise ( ( < 1 0 ) ( ^ x 1 ) ) ( ( >= 2 2 ) ( + ( ^ x 2 ) ( ^ x 1 ) ) ) ( else 5 ) )")
+	s, err = Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( ^ x 1 ) ( ^ x 2 ) )", s.ToSExp().String())
+}
+
+func TestEvaluatePiecewise(t *testing.T) {
+	poly := polyFromString(t, hinge)
+	for x, expected := range map[float64]float64{-1: 2, 0.5: 0.5, 1: 0, 3: 0} {
+		v, err := Evaluate(poly, map[Symbol]float64{"x": x})
+		assert.NoError(t, err)
+		assert.InDelta(t, expected, v, 1e-9)
+	}
+
+	poly = polyFromString(t, "( + ( abs ( ^ x 1 ) ) ( sign ( ^ x 1 ) ) )")
+	v, err := Evaluate(poly, map[Symbol]float64{"x": -3})
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, v)
+
+	// no else piece
+	poly = polyFromString(t, "( piecewise ( ( > ( ^ x 1 ) 0 ) 1 ) )")
+	_, err = Evaluate(poly, map[Symbol]float64{"x": -3})
+	assert.Error(t, err)
+}
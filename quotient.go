@@ -0,0 +1,340 @@
+package symdiff
+
+import (
+	"fmt"
+)
+
+/*
+   Rational functions
+
+   Quotients are written ( / n d ), sugar for ( * 1 n ( ^ d -1 ) ), and
+   expressions built from constants, one variable, sums, products and integer
+   powers are rational functions n / d of polynomials n and d.
+
+   Together combines such an expression into a single quotient with the GCD
+   of numerator and denominator cancelled, Cancel does the same for each term
+   of a sum separately and Apart decomposes into partial fractions over the
+   irreducible factors of the denominator, see Factor.
+
+     ( + ( / 1 ( ^ x 1 ) ) ( / 1 ( + ( ^ x 1 ) 1 ) ) )
+       ==> Together ( * 1 ( + ( * 2 ( ^ x 1 ) ) 1 ) ( ^ ( + ( ^ x 1 ) ( ^ x 2 ) ) -1 ) )
+       ==> Apart    ( + ( ^ x -1 ) ( ^ ( + ( ^ x 1 ) 1 ) -1 ) )
+
+   Denominators are written with coprime integer coefficients and a positive
+   leading coefficient.  Numerators and denominators are dense polynomials,
+   exponents and degrees of powers above MaxDegree are rejected.
+*/
+
+// Quotient of polynomials
+// Invariant: den is primitive with a positive leading coefficient and
+// coprime to num
+type ratfunc struct {
+	num upoly
+	den upoly
+}
+
+// Single quotient with the GCD of numerator and denominator cancelled
+func Together(poly PolyExp) (*PolyExp, error) {
+	if err := poly.check(); err != nil {
+		return nil, err
+	}
+	var x Symbol
+	r, err := toRatfunc(poly, &x)
+	if err != nil {
+		return nil, fmt.Errorf("%s, not a rational function in one variable", err)
+	}
+	ret := r.toPolyExp(x)
+	return &ret, nil
+}
+
+// Together applied to each term of a sum, terms that are not rational
+// functions are kept as they are
+func Cancel(poly PolyExp) (*PolyExp, error) {
+	if err := poly.check(); err != nil {
+		return nil, err
+	}
+	terms := Flatten(poly)
+	for i, t := range terms {
+		if together, err := Together(t); err == nil {
+			terms[i] = *together
+		}
+	}
+	return Join(terms), nil
+}
+
+// Partial fraction decomposition over the rationals, the polynomial part
+// followed by numerators of lower degree than their irreducible denominators
+// in order of the factors of the denominator and increasing powers
+func Apart(poly PolyExp) (*PolyExp, error) {
+	if err := poly.check(); err != nil {
+		return nil, err
+	}
+	var x Symbol
+	r, err := toRatfunc(poly, &x)
+	if err != nil {
+		return nil, fmt.Errorf("%s, not a rational function in one variable", err)
+	}
+	whole, rem, err := r.num.divmod(r.den)
+	if err != nil {
+		return nil, err
+	}
+	var terms []PolyExp
+	if whole.degree() >= 0 {
+		terms = append(terms, Flatten(whole.toPolyExp(x))...)
+	}
+	if rem.degree() >= 0 {
+		// the content of the denominator is 1 as it is primitive
+		_, factors, err := factorUnivariate(r.den)
+		if err != nil {
+			return nil, err
+		}
+		fractions, err := partialFractions(rem, factors)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fractions {
+			terms = append(terms, fractionExp(f.num, f.base, f.k, x))
+		}
+	}
+	if len(terms) == 0 {
+		ret := Zero()
+		return &ret, nil
+	}
+	return Join(terms), nil
+}
+
+// Numerator over a power of an irreducible polynomial
+type partialFraction struct {
+	num  upoly
+	base upoly
+	k    int
+}
+
+// Split r / prod factors into fractions over powers of each factor
+// Invariant: r has lower degree than the product of the factors
+func partialFractions(r upoly, factors []upolyFactor) ([]partialFraction, error) {
+	var ret []partialFraction
+	rest := upoly{Integer(1)}
+	for _, f := range factors[1:] {
+		for i := 0; i < f.k; i++ {
+			rest = rest.mul(f.p)
+		}
+	}
+	f := factors[0]
+	power := upoly{Integer(1)}
+	for i := 0; i < f.k; i++ {
+		power = power.mul(f.p)
+	}
+	// a rest + b power = r with deg a < deg power, so
+	// r / (power rest) = a / power + b / rest
+	s, _, err := extendedGCD(rest, power)
+	if err != nil {
+		return nil, err
+	}
+	_, a, err := r.mul(s).divmod(power)
+	if err != nil {
+		return nil, err
+	}
+	b, _, err := r.sub(a.mul(rest)).divmod(power)
+	if err != nil {
+		return nil, err
+	}
+
+	// a = a0 + a1 f + a2 f^2 ... gives a / f^k = a0 / f^k + a1 / f^(k-1) ...
+	for j := f.k; j > 0; j-- {
+		q, c, err := a.divmod(f.p)
+		if err != nil {
+			return nil, err
+		}
+		if c.degree() >= 0 {
+			ret = append(ret, partialFraction{num: c, base: f.p, k: j})
+		}
+		a = q
+	}
+	// increasing powers
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	if len(factors) > 1 && b.degree() >= 0 {
+		rest, err := partialFractions(b, factors[1:])
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, rest...)
+	}
+	return ret, nil
+}
+
+// Bezout coefficients s, t with s p + t q = 1 for coprime p and q
+func extendedGCD(p, q upoly) (upoly, upoly, error) {
+	s0, s1 := upoly{Integer(1)}, upoly(nil)
+	t0, t1 := upoly(nil), upoly{Integer(1)}
+	for q.degree() >= 0 {
+		quo, r, err := p.divmod(q)
+		if err != nil {
+			return nil, nil, err
+		}
+		p, q = q, r
+		s0, s1 = s1, s0.sub(quo.mul(s1))
+		t0, t1 = t1, t0.sub(quo.mul(t1))
+	}
+	// p is the constant GCD
+	inv, err := Integer(1).Div(p.lc())
+	if err != nil {
+		return nil, nil, err
+	}
+	return s0.scale(inv), t0.scale(inv), nil
+}
+
+// Rational function of exp in the variable *x, set by the first monomial
+func toRatfunc(exp PolyExp, x *Symbol) (ratfunc, error) {
+	one := upoly{Integer(1)}
+	switch {
+	case exp.IsConstant():
+		return ratfunc{num: upoly{exp.constant().c}.trim(), den: one}, nil
+	case exp.IsMon():
+		m := exp.mon()
+		if m.e != nil || !m.n.IsInteger() {
+			return ratfunc{}, fmt.Errorf("monomial %s has no integer exponent", exp.ToSExp().String())
+		}
+		if *x != "" && *x != m.x {
+			return ratfunc{}, fmt.Errorf("expression in more than one variable, %s and %s", *x, m.x)
+		}
+		*x = m.x
+		if !m.n.IsInt() || absInt(m.n.Int()) > MaxDegree {
+			return ratfunc{}, fmt.Errorf("exponent of monomial %s exceeds the maximum degree %d", exp.ToSExp().String(), MaxDegree)
+		}
+		n := m.n.Int()
+		if n < 0 {
+			return ratfunc{num: one, den: monomialUpoly(-n)}, nil
+		}
+		return ratfunc{num: monomialUpoly(n), den: one}, nil
+	case exp.IsSum():
+		ret := ratfunc{den: one}
+		for _, t := range exp.sum().Term() {
+			r, err := toRatfunc(t, x)
+			if err != nil {
+				return ratfunc{}, err
+			}
+			ret, err = ratfunc{num: ret.num.mul(r.den).add(r.num.mul(ret.den)), den: ret.den.mul(r.den)}.reduce()
+			if err != nil {
+				return ratfunc{}, err
+			}
+		}
+		return ret, nil
+	case exp.IsProduct():
+		l, fs := exp.product().Term()
+		ret, err := toRatfunc(l, x)
+		if err != nil {
+			return ratfunc{}, err
+		}
+		for _, f := range fs {
+			r, err := toRatfunc(f, x)
+			if err != nil {
+				return ratfunc{}, err
+			}
+			ret, err = ratfunc{num: ret.num.mul(r.num), den: ret.den.mul(r.den)}.reduce()
+			if err != nil {
+				return ratfunc{}, err
+			}
+		}
+		return ret, nil
+	case exp.IsPower():
+		b, n := exp.power().Term()
+		if !n.IsInteger() {
+			return ratfunc{}, fmt.Errorf("power %s has no integer exponent", exp.ToSExp().String())
+		}
+		if !n.IsInt() || absInt(n.Int()) > MaxDegree {
+			return ratfunc{}, fmt.Errorf("exponent of power %s exceeds the maximum degree %d", exp.ToSExp().String(), MaxDegree)
+		}
+		r, err := toRatfunc(b, x)
+		if err != nil {
+			return ratfunc{}, err
+		}
+		k := n.Int()
+		d := r.num.degree()
+		if r.den.degree() > d {
+			d = r.den.degree()
+		}
+		if uint64(d)*absInt(k) > MaxDegree {
+			return ratfunc{}, fmt.Errorf("degree of power %s exceeds the maximum degree %d", exp.ToSExp().String(), MaxDegree)
+		}
+		if k < 0 {
+			if r.num.degree() < 0 {
+				return ratfunc{}, fmt.Errorf("division by zero in %s", exp.ToSExp().String())
+			}
+			r, k = ratfunc{num: r.den, den: r.num}, -k
+		}
+		ret := ratfunc{num: one, den: one}
+		for i := 0; i < k; i++ {
+			ret = ratfunc{num: ret.num.mul(r.num), den: ret.den.mul(r.den)}
+		}
+		return ret.reduce()
+	}
+	return ratfunc{}, fmt.Errorf("%s is not built from constants, a variable, sums, products and integer powers", exp.ToSExp().String())
+}
+
+// x^n
+func monomialUpoly(n int) upoly {
+	ret := make(upoly, n+1)
+	for i := range ret {
+		ret[i] = Integer(0)
+	}
+	ret[n] = Integer(1)
+	return ret
+}
+
+// Cancel the GCD and normalize the denominator
+// Invariant: r.den is not zero
+func (r ratfunc) reduce() (ratfunc, error) {
+	if r.num.degree() < 0 {
+		return ratfunc{den: upoly{Integer(1)}}, nil
+	}
+	g, err := r.num.gcd(r.den)
+	if err != nil {
+		return ratfunc{}, err
+	}
+	num, _, err := r.num.divmod(g)
+	if err != nil {
+		return ratfunc{}, err
+	}
+	den, _, err := r.den.divmod(g)
+	if err != nil {
+		return ratfunc{}, err
+	}
+	content, den := den.primitive()
+	inv, err := Integer(1).Div(content)
+	if err != nil {
+		return ratfunc{}, err
+	}
+	return ratfunc{num: num.scale(inv), den: den}, nil
+}
+
+func (r ratfunc) toPolyExp(x Symbol) PolyExp {
+	if r.den.degree() == 0 {
+		return r.num.toPolyExp(x)
+	}
+	return fractionExp(r.num, r.den, 1, x)
+}
+
+// num / base^k written as a product with a negative power, powers of x are
+// monomials and the content of num is the coefficient
+func fractionExp(num, base upoly, k int, x Symbol) PolyExp {
+	var inv PolyExp
+	if m := base.degree(); m > 0 && base.lc().Equal(Integer(1)) && len(base[:m].trim()) == 0 {
+		inv = PolyExp{e: &MonomialExp{x: x, n: Integer(-m * k)}}
+	} else {
+		b := base.toPolyExp(x)
+		inv = PolyExp{e: &PowerExp{b: &b, n: Integer(-k)}}
+	}
+	if num.degree() == 0 {
+		if num[0].Equal(Integer(1)) {
+			return inv
+		}
+		return PolyExp{e: &ProductExp{l: &PolyExp{e: &ConstantExp{c: num[0]}}, r: &inv}}
+	}
+	// the content of the numerator is the coefficient
+	content, prim := num.primitive()
+	n := prim.toPolyExp(x)
+	return PolyExp{e: &ProductExp{l: &PolyExp{e: &ConstantExp{c: content}}, r: &n, fs: []PolyExp{inv}}}
+}
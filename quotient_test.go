@@ -0,0 +1,115 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestQuotientSugar(t *testing.T) {
+	p := polyFromString(t, "( / ( + ( ^ x 1 ) 1 ) ( + ( ^ x 1 ) -1 ) )")
+	assert.Equal(t, "( * 1 ( + ( ^ x 1 ) 1 ) ( ^ ( + ( ^ x 1 ) -1 ) -1 ) )", p.ToSExp().String())
+	p = polyFromString(t, "( / a ( ^ x 2 ) )", "a")
+	assert.Equal(t, "( * a ( ^ ( ^ x 2 ) -1 ) )", p.ToSExp().String())
+	v, err := Evaluate(p, map[Symbol]float64{"a": 3, "x": 2})
+	require.NoError(t, err)
+	assert.Equal(t, 0.75, v)
+
+	var sexp SExp
+	require.NoError(t, sexp.Parse("( / 1 )"))
+	assert.Error(t, p.Parse(sexp))
+}
+
+func TestTogether(t *testing.T) {
+	cases := []struct {
+		raw, expected string
+	}{
+		{"( + ( / 1 ( ^ x 1 ) ) ( / 1 ( + ( ^ x 1 ) 1 ) ) )", "( * 1 ( + ( * 2 ( ^ x 1 ) ) 1 ) ( ^ ( + ( ^ x 1 ) ( ^ x 2 ) ) -1 ) )"},
+		{"( / ( + ( ^ x 2 ) -1 ) ( + ( ^ x 1 ) 1 ) )", "( + ( ^ x 1 ) -1 )"},
+		{"( / ( + ( ^ x 1 ) 1 ) ( + ( * 2 ( ^ x 2 ) ) ( * 2 ( ^ x 1 ) ) ) )", "( * 1/2 ( ^ x -1 ) )"},
+		{"( + ( ^ x -2 ) ( ^ x -1 ) )", "( * 1 ( + ( ^ x 1 ) 1 ) ( ^ x -2 ) )"},
+		{"( / 3 ( + ( * 2 ( ^ x 1 ) ) 4 ) )", "( * 3/2 ( ^ ( + ( ^ x 1 ) 2 ) -1 ) )"},
+		{"( + ( / 1 ( + ( ^ x 1 ) 1 ) ) ( / -1 ( + ( ^ x 1 ) 1 ) ) )", "0"},
+		{"( ^ ( / ( ^ x 1 ) ( + ( ^ x 1 ) 1 ) ) -2 )", "( * 1 ( + ( * 2 ( ^ x 1 ) ) ( ^ x 2 ) 1 ) ( ^ x -2 ) )"},
+	}
+	for _, c := range cases {
+		poly := polyFromString(t, c.raw)
+		out, err := Together(poly)
+		require.NoError(t, err, c.raw)
+		assert.Equal(t, c.expected, out.ToSExp().String(), c.raw)
+		eq, err := Equivalent(poly, *out)
+		require.NoError(t, err)
+		assert.True(t, eq.Equivalent, c.raw)
+	}
+
+	for _, raw := range []string{
+		"( / 1 ( + ( ^ x 1 ) ( * -1 ( ^ x 1 ) ) ) )",
+		"( / 1 ( + ( ^ x 1 ) ( ^ y 1 ) ) )",
+		"( / a ( ^ x 1 ) )",
+		"( sqrt ( ^ x 1 ) )",
+		// degrees are bounded before allocating coefficients
+		"( * 1 1 ( ^ ( ^ x 1000000000 ) -1 ) )",
+		"( ^ ( + ( ^ x 1000 ) 1 ) 1000 )",
+		"( ^ ( + ( ^ x 1 ) 1 ) 10000000000 )",
+	} {
+		_, err := Together(polyFromString(t, raw))
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	poly := polyFromString(t, "( + ( / ( + ( ^ x 2 ) -1 ) ( + ( ^ x 1 ) 1 ) ) ( / 1 ( ^ x 1 ) ) ( abs ( ^ x 1 ) ) )")
+	out, err := Cancel(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( + ( ^ x 1 ) -1 ) ( ^ x -1 ) ( abs ( ^ x 1 ) ) )", out.ToSExp().String())
+}
+
+func TestApart(t *testing.T) {
+	cases := []struct {
+		raw, expected string
+	}{
+		{"( / ( + ( * 2 ( ^ x 1 ) ) 1 ) ( + ( ^ x 2 ) ( ^ x 1 ) ) )", "( + ( ^ x -1 ) ( ^ ( + ( ^ x 1 ) 1 ) -1 ) )"},
+		{"( / 1 ( + ( ^ x 2 ) -1 ) )", "( + ( * 1/2 ( ^ ( + ( ^ x 1 ) -1 ) -1 ) ) ( * -1/2 ( ^ ( + ( ^ x 1 ) 1 ) -1 ) ) )"},
+		// polynomial part
+		{"( / ( + ( ^ x 3 ) 1 ) ( + ( ^ x 1 ) -1 ) )", "( + ( ^ x 1 ) ( ^ x 2 ) 1 ( * 2 ( ^ ( + ( ^ x 1 ) -1 ) -1 ) ) )"},
+		// repeated and irreducible quadratic factors
+		{"( / ( + ( ^ x 2 ) 1 ) ( * 1 ( ^ ( + ( ^ x 1 ) 1 ) 2 ) ( ^ x 1 ) ) )", "( + ( ^ x -1 ) ( * -2 ( ^ ( + ( ^ x 1 ) 1 ) -2 ) ) )"},
+		{"( / 1 ( * 1 ( ^ x 1 ) ( + ( ^ x 2 ) 1 ) ) )", "( + ( ^ x -1 ) ( * -1 ( ^ x 1 ) ( ^ ( + ( ^ x 2 ) 1 ) -1 ) ) )"},
+		{"( + ( ^ x 2 ) 1 )", "( + ( ^ x 2 ) 1 )"},
+		{"0", "0"},
+	}
+	for _, c := range cases {
+		poly := polyFromString(t, c.raw)
+		out, err := Apart(poly)
+		require.NoError(t, err, c.raw)
+		assert.Equal(t, c.expected, out.ToSExp().String(), c.raw)
+		eq, err := Equivalent(poly, *out)
+		require.NoError(t, err)
+		assert.True(t, eq.Equivalent, c.raw)
+	}
+}
+
+func TestSimplifyRationalStages(t *testing.T) {
+	poly := polyFromString(t, "( + ( / 1 ( ^ x 1 ) ) ( / 1 ( + ( ^ x 1 ) 1 ) ) )")
+	s := NewSession()
+	require.NoError(t, s.Enable("together"))
+	out, err := s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( * 1 ( + ( * 2 ( ^ x 1 ) ) 1 ) ( ^ ( + ( ^ x 1 ) ( ^ x 2 ) ) -1 ) )", out.ToSExp().String())
+
+	// terms that are not rational functions are kept
+	out, err = s.Simplify(polyFromString(t, "( + ( / ( + ( ^ x 2 ) -1 ) ( + ( ^ x 1 ) 1 ) ) ( abs ( ^ x 1 ) ) )"))
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( ^ x 1 ) ( abs ( ^ x 1 ) ) -1 )", out.ToSExp().String())
+
+	require.NoError(t, s.Disable("together"))
+	require.NoError(t, s.Enable("apart"))
+	out, err = s.Simplify(out)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( ^ x 1 ) ( abs ( ^ x 1 ) ) -1 )", out.ToSExp().String())
+	out, err = s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( ^ x -1 ) ( ^ ( + ( ^ x 1 ) 1 ) -1 ) )", out.ToSExp().String())
+}
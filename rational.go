@@ -0,0 +1,284 @@
+package symdiff
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"strings"
+)
+
+// Exact rational number num/den used for constants and exponents, held in
+// machine integers while they fit and in math/big beyond
+// Invariant: den > 0 and gcd(num, den) == 1, big is nil exactly when the
+// value fits, construct with NewRational, Integer or ParseRational
+type Rational struct {
+	num int
+	den int
+	// never modified once set, results are fresh values
+	big *big.Rat
+}
+
+func Integer(n int) Rational {
+	return Rational{num: n, den: 1}
+}
+
+func NewRational(num, den int) (Rational, error) {
+	if den == 0 {
+		return Rational{}, fmt.Errorf("zero denominator in rational %d/%d", num, den)
+	}
+	if num == math.MinInt || den == math.MinInt {
+		// can't be negated
+		return fromRat(big.NewRat(int64(num), int64(den))), nil
+	}
+	if den < 0 {
+		num, den = -num, -den
+	}
+	g := gcd(num, den)
+	return Rational{num: num / g, den: den / g}, nil
+}
+
+// Rational of a math/big value, small when it fits
+func fromRat(x *big.Rat) Rational {
+	num, den := x.Num(), x.Denom()
+	if num.IsInt64() && den.IsInt64() && fitsInt(num.Int64()) && fitsInt(den.Int64()) {
+		return Rational{num: int(num.Int64()), den: int(den.Int64())}
+	}
+	return Rational{big: new(big.Rat).Set(x)}
+}
+
+func fitsInt(n int64) bool {
+	return n >= math.MinInt && n <= math.MaxInt
+}
+
+// Rational of an integer of any size
+func intRational(n *big.Int) Rational {
+	return fromRat(new(big.Rat).SetInt(n))
+}
+
+// Value as math/big, the zero Rational is 0
+func (r Rational) rat() *big.Rat {
+	if r.big != nil {
+		return r.big
+	}
+	if r.den == 0 {
+		return new(big.Rat)
+	}
+	return big.NewRat(int64(r.num), int64(r.den))
+}
+
+// Parse integer "n" or rational "n/d" strings of any size
+func ParseRational(s string) (Rational, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) > 2 {
+		return Rational{}, fmt.Errorf("invalid rational %s", s)
+	}
+	num, ok := new(big.Int).SetString(parts[0], 10)
+	if !ok {
+		return Rational{}, fmt.Errorf("invalid numerator in rational %s", s)
+	}
+	den := big.NewInt(1)
+	if len(parts) == 2 {
+		if den, ok = new(big.Int).SetString(parts[1], 10); !ok {
+			return Rational{}, fmt.Errorf("invalid denominator in rational %s", s)
+		}
+	}
+	if den.Sign() == 0 {
+		return Rational{}, fmt.Errorf("zero denominator in rational %s", s)
+	}
+	return fromRat(new(big.Rat).SetFrac(num, den)), nil
+}
+
+func gcd(a, b int) int {
+	if a < 0 {
+		a = -a
+	}
+	if b < 0 {
+		b = -b
+	}
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a == 0 {
+		return 1
+	}
+	return a
+}
+
+// Getter for numerator and denominator
+func (r Rational) Frac() (*big.Int, *big.Int) {
+	x := r.rat()
+	return new(big.Int).Set(x.Num()), new(big.Int).Set(x.Denom())
+}
+
+func (r Rational) IsInteger() bool {
+	if r.big != nil {
+		return r.big.IsInt()
+	}
+	return r.den == 1
+}
+
+// Whether r is an integer fitting an int, see Int
+func (r Rational) IsInt() bool {
+	return r.big == nil && r.den == 1
+}
+
+// Integer part of r truncated towards zero
+// Invariant: the integer part fits an int, values too large to be exponents
+// or counts are rejected before
+func (r Rational) Int() int {
+	if r.big != nil {
+		return int(new(big.Int).Quo(r.big.Num(), r.big.Denom()).Int64())
+	}
+	return r.num / r.den
+}
+
+func (r Rational) IsZero() bool {
+	return r.Sign() == 0
+}
+
+func (r Rational) Sign() int {
+	if r.big != nil {
+		return r.big.Sign()
+	}
+	switch {
+	case r.num < 0:
+		return -1
+	case r.num > 0:
+		return 1
+	}
+	return 0
+}
+
+func (r Rational) Add(s Rational) Rational {
+	if r.big == nil && s.big == nil {
+		a, ok1 := mulInt(r.num, s.den)
+		b, ok2 := mulInt(s.num, r.den)
+		num, ok3 := addInt(a, b)
+		den, ok4 := mulInt(r.den, s.den)
+		if ok1 && ok2 && ok3 && ok4 {
+			q, _ := NewRational(num, den)
+			return q
+		}
+	}
+	return fromRat(new(big.Rat).Add(r.rat(), s.rat()))
+}
+
+func (r Rational) Sub(s Rational) Rational {
+	return r.Add(s.Neg())
+}
+
+func (r Rational) Mul(s Rational) Rational {
+	if r.big == nil && s.big == nil {
+		num, ok1 := mulInt(r.num, s.num)
+		den, ok2 := mulInt(r.den, s.den)
+		if ok1 && ok2 {
+			q, _ := NewRational(num, den)
+			return q
+		}
+	}
+	return fromRat(new(big.Rat).Mul(r.rat(), s.rat()))
+}
+
+func (r Rational) Div(s Rational) (Rational, error) {
+	if s.IsZero() {
+		return Rational{}, fmt.Errorf("division of %s by zero", r)
+	}
+	return fromRat(new(big.Rat).Quo(r.rat(), s.rat())), nil
+}
+
+func (r Rational) Neg() Rational {
+	if r.big != nil || r.num == math.MinInt {
+		return fromRat(new(big.Rat).Neg(r.rat()))
+	}
+	return Rational{num: -r.num, den: r.den}
+}
+
+func (r Rational) Equal(s Rational) bool {
+	if r.big == nil && s.big == nil {
+		return r.num == s.num && r.den == s.den
+	}
+	return r.rat().Cmp(s.rat()) == 0
+}
+
+func (r Rational) Less(s Rational) bool {
+	return r.rat().Cmp(s.rat()) < 0
+}
+
+func (r Rational) Float64() float64 {
+	if r.big != nil {
+		f, _ := r.big.Float64()
+		return f
+	}
+	return float64(r.num) / float64(r.den)
+}
+
+func (r Rational) String() string {
+	if r.big != nil {
+		return r.big.RatString()
+	}
+	if r.den == 1 {
+		return fmt.Sprintf("%d", r.num)
+	}
+	return fmt.Sprintf("%d/%d", r.num, r.den)
+}
+
+// Bound on the bits of numerator and denominator of powers computed by Pow
+const MaxPowBits = 1 << 20
+
+// Raise r to the integer power n, powers of 0 and ±1 are exact and other
+// powers are rejected above MaxPowBits
+func (r Rational) Pow(n int) (Rational, error) {
+	base := r
+	if n < 0 {
+		inv, err := Integer(1).Div(r)
+		if err != nil {
+			return Rational{}, err
+		}
+		base, n = inv, -n
+	}
+	x := base.rat()
+	bits := x.Num().BitLen()
+	if d := x.Denom().BitLen(); d > bits {
+		bits = d
+	}
+	if (bits > 1 || !x.IsInt()) && uint64(n) > MaxPowBits/uint64(bits) {
+		return Rational{}, fmt.Errorf("power %s^%d exceeds %d bits", r.String(), n, MaxPowBits)
+	}
+	k := big.NewInt(int64(n))
+	num := new(big.Int).Exp(x.Num(), k, nil)
+	den := new(big.Int).Exp(x.Denom(), k, nil)
+	return fromRat(new(big.Rat).SetFrac(num, den)), nil
+}
+
+// a * b reporting false on overflow
+func mulInt(a, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	hi, lo := bits.Mul64(absInt(a), absInt(b))
+	neg := (a < 0) != (b < 0)
+	if hi != 0 || lo > math.MaxInt {
+		return 0, false
+	}
+	if neg {
+		return -int(lo), true
+	}
+	return int(lo), true
+}
+
+// a + b reporting false on overflow
+func addInt(a, b int) (int, bool) {
+	c := a + b
+	if (c > a) != (b > 0) {
+		return 0, false
+	}
+	return c, true
+}
+
+func absInt(a int) uint64 {
+	if a < 0 {
+		return uint64(-a)
+	}
+	return uint64(a)
+}
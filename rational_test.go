@@ -0,0 +1,59 @@
+package symdiff_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestRationalArithmeticDoesNotOverflow(t *testing.T) {
+	big := Integer(99999999999)
+	assert.Equal(t, "9999999999800000000001", big.Mul(big).String())
+	assert.Equal(t, "9223372036854775808", Integer(math.MaxInt).Add(Integer(1)).String())
+	assert.Equal(t, "9223372036854775808", Integer(math.MinInt).Neg().String())
+	p, err := Integer(10).Pow(30)
+	require.NoError(t, err)
+	assert.Equal(t, "1000000000000000000000000000000", p.String())
+	inv, err := Integer(-10).Pow(-21)
+	require.NoError(t, err)
+	assert.Equal(t, "-1/1000000000000000000000", inv.String())
+	// powers are bounded, except for 0 and ±1
+	_, err = Integer(2).Pow(100000000000)
+	assert.Error(t, err)
+	third, err := NewRational(1, 3)
+	require.NoError(t, err)
+	_, err = third.Pow(-1 << 30)
+	assert.Error(t, err)
+	p, err = Integer(-1).Pow(100000000001)
+	require.NoError(t, err)
+	assert.Equal(t, "-1", p.String())
+
+	// results that fit again compare equal to small values
+	q, err := big.Mul(big).Div(big)
+	require.NoError(t, err)
+	assert.Equal(t, big, q)
+	assert.True(t, big.Mul(big).Sub(big.Mul(big)).IsZero())
+	assert.True(t, Integer(1).Less(big.Mul(big)))
+
+	r, err := ParseRational("-123456789012345678901234567890/10")
+	require.NoError(t, err)
+	assert.Equal(t, "-12345678901234567890123456789", r.String())
+	_, err = ParseRational("1/0")
+	assert.Error(t, err)
+	_, err = ParseRational("1.5")
+	assert.Error(t, err)
+}
+
+func TestSimplifyLargeCoefficients(t *testing.T) {
+	s, err := Simplify(polyFromString(t, "( * 99999999999 ( * 99999999999 ( ^ x 1 ) ) )"))
+	require.NoError(t, err)
+	assert.Equal(t, "( * 9999999999800000000001 ( ^ x 1 ) )", s.ToSExp().String())
+
+	// constant powers too large to fold stay symbolic
+	s, err = Simplify(polyFromString(t, "( + ( ^ 2 100000000000 ) ( ^ 2 10 ) ( ^ 1 100000000000 ) )"))
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( ^ 2 100000000000 ) 1025 )", s.ToSExp().String())
+}
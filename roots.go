@@ -0,0 +1,318 @@
+package symdiff
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+	"strings"
+)
+
+/*
+   Roots of univariate polynomials
+
+   The polynomial is factored over the integers, see Factor, and the roots of
+   each irreducible factor are found
+   - exactly for linear factors, the rational roots
+   - exactly for quadratic factors, ( -b +- s sqrt(d) ) / 2a with the
+     discriminant b^2 - 4ac = s^2 d and d square free
+   - numerically for factors of higher degree, real roots by bisecting Sturm
+     isolation intervals and the others by the Aberth-Ehrlich iteration
+
+     ( + ( ^ x 3 ) ( * -1 ( ^ x 1 ) ) -1 ) has the real root 1.324717957244746
+     and the complex roots -0.662358978622373 +- 0.5622795120623012 i
+
+   Every root is reported once with the multiplicity of its factor, real roots
+   first in increasing order then complex roots by real and imaginary part.
+*/
+
+// Iterations of the Aberth-Ehrlich method before giving up on convergence
+const MaxAberthIterations = 500
+
+// Root of a polynomial with its multiplicity
+type Root struct {
+	Re, Im       float64
+	Multiplicity int
+	// Closed forms of the real and imaginary part for rational and quadratic
+	// roots, nil for numeric roots
+	ExactRe, ExactIm *PolyExp
+}
+
+func (r Root) IsReal() bool {
+	return r.Im == 0
+}
+
+func (r Root) IsExact() bool {
+	return r.ExactRe != nil
+}
+
+// Closed form when exact followed by the numeric value, i.e.
+// ( + ( * 1/2 ( ^ 5 1/2 ) ) -1/2 ) ~ 0.6180339887498949
+// -1/2 - ( * 1/2 ( ^ 3 1/2 ) ) i ~ (-0.5-0.8660254037844386i)
+func (r Root) String() string {
+	var b strings.Builder
+	if r.IsExact() {
+		re := r.ExactRe.ToSExp().String()
+		switch {
+		case r.IsReal():
+			b.WriteString(re)
+		case r.ExactRe.IsConstant() && r.ExactRe.constant().c.IsZero():
+			fmt.Fprintf(&b, "%s i", r.ExactIm.ToSExp().String())
+		default:
+			if im, ok := negateExact(*r.ExactIm); ok && r.Im < 0 {
+				fmt.Fprintf(&b, "%s - %s i", re, im.ToSExp().String())
+			} else {
+				fmt.Fprintf(&b, "%s + %s i", re, r.ExactIm.ToSExp().String())
+			}
+		}
+		b.WriteString(" ~ ")
+	} else {
+		b.WriteString("~ ")
+	}
+	if r.IsReal() {
+		fmt.Fprintf(&b, "%v", r.Re)
+	} else {
+		fmt.Fprintf(&b, "%v", complex(r.Re, r.Im))
+	}
+	if r.Multiplicity > 1 {
+		fmt.Fprintf(&b, " multiplicity %d", r.Multiplicity)
+	}
+	return b.String()
+}
+
+// Real and complex roots of a polynomial in one variable with rational
+// coefficients
+func Roots(poly PolyExp) ([]Root, error) {
+	p, err := rootPolynomial(poly)
+	if err != nil {
+		return nil, err
+	}
+	if p.degree() < 1 {
+		return nil, nil
+	}
+	_, factors, err := factorUnivariate(p)
+	if err != nil {
+		return nil, err
+	}
+	var ret []Root
+	for _, f := range factors {
+		var rs []Root
+		switch f.p.degree() {
+		case 1:
+			root, err := linearRoot(f.p)
+			if err != nil {
+				return nil, err
+			}
+			rs = []Root{root}
+		case 2:
+			rs, err = quadraticRoots(f.p)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			rs, err = numericRoots(f.p)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for i := range rs {
+			rs[i].Multiplicity = f.k
+		}
+		ret = append(ret, rs...)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].IsReal() != ret[j].IsReal() {
+			return ret[i].IsReal()
+		}
+		if ret[i].Re != ret[j].Re {
+			return ret[i].Re < ret[j].Re
+		}
+		return ret[i].Im < ret[j].Im
+	})
+	return ret, nil
+}
+
+// -e for a constant or a constant multiple, as closed forms are built
+func negateExact(e PolyExp) (PolyExp, bool) {
+	switch {
+	case e.IsConstant():
+		return Const(e.constant().c.Neg()), true
+	case e.IsProduct() && e.product().l.IsConstant() && len(e.product().fs) == 0:
+		k := e.product().l.constant().c.Neg()
+		if k.Equal(Integer(1)) {
+			return *e.product().r, true
+		}
+		return PolyExp{e: &ProductExp{l: &PolyExp{e: &ConstantExp{c: k}}, r: e.product().r}}, true
+	}
+	return PolyExp{}, false
+}
+
+// Root of a x + b
+func linearRoot(p upoly) (Root, error) {
+	r, err := p[0].Neg().Div(p[1])
+	if err != nil {
+		return Root{}, err
+	}
+	re, im := Const(r), Zero()
+	return Root{Re: r.Float64(), ExactRe: &re, ExactIm: &im}, nil
+}
+
+// Roots of an irreducible a x^2 + b x + c, the smaller real or the lower
+// complex root first
+func quadraticRoots(p upoly) ([]Root, error) {
+	a, b, c := p[2], p[1], p[0]
+	disc := b.Mul(b).Sub(Integer(4).Mul(a).Mul(c))
+	twoA := Integer(2).Mul(a)
+	center, err := b.Neg().Div(twoA)
+	if err != nil {
+		return nil, err
+	}
+
+	// |disc| = s^2 d with d square free, p is an integer polynomial.  Square
+	// factors are only taken out of discriminants up to MaxTrialDivision
+	s, d := Integer(1), disc
+	if disc.Sign() < 0 {
+		d = disc.Neg()
+	}
+	if d.IsInt() && d.Int() <= MaxTrialDivision {
+		si, di := 1, d.Int()
+		for k := 2; k*k <= di; k++ {
+			for di%(k*k) == 0 {
+				si, di = si*k, di/(k*k)
+			}
+		}
+		s, d = Integer(si), Integer(di)
+	}
+	scale, err := s.Div(twoA)
+	if err != nil {
+		return nil, err
+	}
+	if scale.Sign() < 0 {
+		scale = scale.Neg()
+	}
+	sqrt := math.Sqrt(d.Float64())
+
+	var ret []Root
+	for _, sign := range []int{-1, 1} {
+		k := scale.Mul(Integer(sign))
+		// k sqrt(d)
+		term := Const(k)
+		if !d.Equal(Integer(1)) {
+			half, err := NewRational(1, 2)
+			if err != nil {
+				return nil, err
+			}
+			if term, err = Raise(Const(d), half); err != nil {
+				return nil, err
+			}
+			if !k.Equal(Integer(1)) {
+				if term, err = Mul(Const(k), term); err != nil {
+					return nil, err
+				}
+			}
+		}
+		r := Root{Re: center.Float64()}
+		if disc.Sign() > 0 {
+			r.Re += k.Float64() * sqrt
+			re := term
+			if !center.IsZero() {
+				if re, err = Sum(term, Const(center)); err != nil {
+					return nil, err
+				}
+			}
+			im := Zero()
+			r.ExactRe, r.ExactIm = &re, &im
+		} else {
+			r.Im = k.Float64() * sqrt
+			re := Const(center)
+			r.ExactRe, r.ExactIm = &re, &term
+		}
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
+
+// Roots of an irreducible integer polynomial of degree 3 or more, the real
+// roots refined from Sturm isolation intervals and the complex roots from
+// the Aberth-Ehrlich iteration
+func numericRoots(p upoly) ([]Root, error) {
+	if p.degree() < 1 {
+		return nil, fmt.Errorf("no roots to approximate of a constant polynomial")
+	}
+	var ret []Root
+	s, err := sturmSequence(p)
+	if err != nil {
+		return nil, err
+	}
+	bound, err := rootBound(p)
+	if err != nil {
+		return nil, err
+	}
+	intervals, err := s.isolate(bound)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range intervals {
+		ret = append(ret, Root{Re: s.refine(r)})
+	}
+	complexRoots := aberth(p, bound)
+	// complex roots come in conjugate pairs, the real roots are those with
+	// the smallest imaginary parts
+	sort.Slice(complexRoots, func(i, j int) bool {
+		return imag(complexRoots[i]) > imag(complexRoots[j])
+	})
+	for _, z := range complexRoots[:(p.degree()-len(ret))/2] {
+		ret = append(ret, Root{Re: real(z), Im: imag(z)}, Root{Re: real(z), Im: -imag(z)})
+	}
+	return ret, nil
+}
+
+// Simultaneous approximation of all roots of a square free polynomial with
+// roots bounded by bound
+func aberth(p upoly, bound float64) []complex128 {
+	n := p.degree()
+	coeffs := make([]complex128, len(p))
+	for i, c := range p {
+		coeffs[i] = complex(c.Float64(), 0)
+	}
+	dp := p.derivative()
+	dcoeffs := make([]complex128, len(dp))
+	for i, c := range dp {
+		dcoeffs[i] = complex(c.Float64(), 0)
+	}
+	// start on a circle inside the root bound, off the real axis
+	radius := bound / 2
+	zs := make([]complex128, n)
+	for k := range zs {
+		zs[k] = cmplx.Rect(radius, 2*math.Pi*float64(k)/float64(n)+0.4)
+	}
+	for it := 0; it < MaxAberthIterations; it++ {
+		converged := true
+		for k := range zs {
+			ratio := hornerComplex(coeffs, zs[k]) / hornerComplex(dcoeffs, zs[k])
+			var repulsion complex128
+			for j := range zs {
+				if j != k {
+					repulsion += 1 / (zs[k] - zs[j])
+				}
+			}
+			w := ratio / (1 - ratio*repulsion)
+			zs[k] -= w
+			if cmplx.Abs(w) > 1e-14*math.Max(1, cmplx.Abs(zs[k])) {
+				converged = false
+			}
+		}
+		if converged {
+			break
+		}
+	}
+	return zs
+}
+
+func hornerComplex(coeffs []complex128, z complex128) complex128 {
+	var ret complex128
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		ret = ret*z + coeffs[i]
+	}
+	return ret
+}
@@ -0,0 +1,94 @@
+package symdiff_test
+
+import (
+	"math"
+	"math/cmplx"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestRoots(t *testing.T) {
+	cases := []struct {
+		raw      string
+		expected []string
+	}{
+		{"( * 1 ( ^ ( + ( ^ x 1 ) -2 ) 2 ) ( + ( * 3 ( ^ x 1 ) ) 1 ) )", []string{"-1/3 ~ -0.3333333333333333", "2 ~ 2 multiplicity 2"}},
+		{"( + ( ^ x 2 ) ( * -1 ( ^ x 1 ) ) -1 )", []string{
+			"( + ( * -1/2 ( ^ 5 1/2 ) ) 1/2 ) ~ -0.6180339887498949",
+			"( + ( * 1/2 ( ^ 5 1/2 ) ) 1/2 ) ~ 1.618033988749895",
+		}},
+		{"( + ( ^ x 2 ) -8 )", []string{"( * -2 ( ^ 2 1/2 ) ) ~ -2.8284271247461903", "( * 2 ( ^ 2 1/2 ) ) ~ 2.8284271247461903"}},
+		{"( + ( ^ x 2 ) ( ^ x 1 ) 1 )", []string{
+			"-1/2 - ( * 1/2 ( ^ 3 1/2 ) ) i ~ (-0.5-0.8660254037844386i)",
+			"-1/2 + ( * 1/2 ( ^ 3 1/2 ) ) i ~ (-0.5+0.8660254037844386i)",
+		}},
+		{"( + ( * 4 ( ^ x 2 ) ) 9 )", []string{"-3/2 i ~ (0-1.5i)", "3/2 i ~ (0+1.5i)"}},
+		{"7", nil},
+	}
+	for _, c := range cases {
+		rs, err := Roots(polyFromString(t, c.raw))
+		require.NoError(t, err, c.raw)
+		var out []string
+		for _, r := range rs {
+			assert.True(t, r.IsExact(), c.raw)
+			out = append(out, r.String())
+		}
+		assert.Equal(t, c.expected, out, c.raw)
+	}
+
+	_, err := Roots(polyFromString(t, "( + ( ^ x 1 ) ( * -1 ( ^ x 1 ) ) )"))
+	assert.Error(t, err)
+	_, err = Roots(polyFromString(t, "( + ( ^ x 2 ) ( ^ y 1 ) )"))
+	assert.Error(t, err)
+	// roots beyond floating point range
+	_, err = Roots(polyFromString(t, "( + ( ^ x 3 ) ( ^ x 1 ) 1"+strings.Repeat("0", 400)+" )"))
+	assert.Error(t, err)
+}
+
+func TestNumericRoots(t *testing.T) {
+	for _, raw := range []string{
+		"( + ( ^ x 3 ) ( * -1 ( ^ x 1 ) ) -1 )",
+		"( + ( ^ x 5 ) ( * -4 ( ^ x 1 ) ) 2 )",
+		"( + ( ^ x 4 ) 1 )",
+		"( * 1 ( + ( ^ x 3 ) -2 ) ( + ( ^ x 3 ) -2 ) ( + ( ^ x 1 ) 1 ) )",
+		// degree 20, intermediate coefficients overflow machine integers
+		"( + ( ^ x 20 ) -1 )",
+		"( * 1000000 ( + ( ^ x 20 ) -1 ) )",
+		"( + ( ^ x 20 ) -1000000 )",
+	} {
+		poly := polyFromString(t, raw)
+		rs, err := Roots(poly)
+		require.NoError(t, err, raw)
+		n, err := poly.Degree("x")
+		require.NoError(t, err)
+		total := 0
+		for _, r := range rs {
+			total += r.Multiplicity
+			// roots are accurate to rounding
+			cs, err := poly.Coefficients("x")
+			require.NoError(t, err)
+			var v, scale complex128
+			z := complex(r.Re, r.Im)
+			for i := len(cs) - 1; i >= 0; i-- {
+				v = v*z + complex(cs[i].Float64(), 0)
+				scale = scale*complex(cmplx.Abs(z), 0) + complex(math.Abs(cs[i].Float64()), 0)
+			}
+			if r.Multiplicity == 1 {
+				assert.Less(t, cmplx.Abs(v), 1e-12*real(scale), raw)
+			}
+		}
+		assert.Equal(t, n, total, raw)
+	}
+
+	rs, err := Roots(polyFromString(t, "( + ( ^ x 3 ) ( * -1 ( ^ x 1 ) ) -1 )"))
+	require.NoError(t, err)
+	require.Len(t, rs, 3)
+	assert.True(t, rs[0].IsReal())
+	assert.False(t, rs[0].IsExact())
+	assert.InDelta(t, 1.324717957244746, rs[0].Re, 1e-15)
+	assert.InDelta(t, rs[1].Im, -rs[2].Im, 1e-15)
+}
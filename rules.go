@@ -0,0 +1,514 @@
+package symdiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+   Rewrite rules
+
+   <rule> ::= <pattern> => <template>
+
+   Patterns are S expressions matched against the printed form of an
+   expression, so they are written with + * and ^ as expressions print.
+   ?name matches any sub expression, repeated uses must match the same sub
+   expression.  ??name matches a run of elements of a list, possibly empty.
+   Segments of one list must be separated by other elements and take the
+   shortest runs that match, leftmost first.  Numbers match numerically equal
+   constants.
+   The template is printed with the matched sub expressions substituted and
+   parsed back into an expression, rules producing invalid expressions fail.
+
+   ( * 1 ?e ) => ?e
+   ( + ?a 0 ) => ?a
+   ( + ?a ?a ??rest ) => ( + ( * 2 ?a ) ??rest )
+   ( + ??a ( + ??b ) ??c ) => ( + ??a ??b ??c )
+
+   Rule files hold one rule per line, blank lines and lines starting with #
+   are skipped.
+
+   Rules are grouped in rule sets which apply them throughout an expression
+   until none applies, see RuleSet.  A rule applies when it changes the
+   expression structurally, a match rewriting into the same expression does
+   not count.  Transformations that are not patterns are built-in passes, see
+   NewPassRule.
+*/
+
+const PatternVarPrefix = "?"
+const RuleArrow = "=>"
+const RuleCommentPrefix = "#"
+
+// Rewrite of expressions at their root
+type Rule interface {
+	// Rewrite exp, reporting false when the rule does not apply
+	Apply(exp PolyExp) (PolyExp, bool, error)
+	String() string
+}
+
+// Rule given as a pattern and a template
+type PatternRule struct {
+	lhs SExp
+	rhs SExp
+}
+
+// Getter for pattern and template of the rule
+// Fields are private to restrict setting to parsing
+func (r *PatternRule) Term() (SExp, SExp) {
+	return r.lhs, r.rhs
+}
+
+func (r *PatternRule) String() string {
+	return r.lhs.String() + " " + RuleArrow + " " + r.rhs.String()
+}
+
+// Parse "<pattern> => <template>"
+func ParseRule(raw string) (*PatternRule, error) {
+	lhs, rhs, ok := strings.Cut(raw, RuleArrow)
+	if !ok {
+		return nil, fmt.Errorf("invalid rule %s, expected <pattern> %s <template>", raw, RuleArrow)
+	}
+	var r PatternRule
+	if err := r.lhs.Parse(lhs); err != nil {
+		return nil, fmt.Errorf("%s, failed to parse pattern of rule %s", err, raw)
+	}
+	if err := r.rhs.Parse(rhs); err != nil {
+		return nil, fmt.Errorf("%s, failed to parse template of rule %s", err, raw)
+	}
+	bound := make(map[string]struct{})
+	if err := checkPattern(r.lhs, bound, false); err != nil {
+		return nil, fmt.Errorf("%s in pattern of rule %s", err, raw)
+	}
+	if err := checkPattern(r.rhs, bound, true); err != nil {
+		return nil, fmt.Errorf("%s in template of rule %s", err, raw)
+	}
+	return &r, nil
+}
+
+// Parse one rule per line skipping blank lines and comments
+func ParseRules(in io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(in)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" || strings.HasPrefix(raw, RuleCommentPrefix) {
+			continue
+		}
+		r, err := ParseRule(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		rules = append(rules, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func isPatternVar(s SExp) bool {
+	return s.Atom != nil && strings.HasPrefix(string(*s.Atom), PatternVarPrefix) && !isSegmentVar(s)
+}
+
+func isSegmentVar(s SExp) bool {
+	return s.Atom != nil && strings.HasPrefix(string(*s.Atom), PatternVarPrefix+PatternVarPrefix)
+}
+
+// Collect variables of a pattern into bound, or check that all variables of
+// a template are bound
+func checkPattern(pat SExp, bound map[string]struct{}, template bool) error {
+	if pat.Atom != nil {
+		if !isPatternVar(pat) && !isSegmentVar(pat) {
+			return nil
+		}
+		if isSegmentVar(pat) {
+			return fmt.Errorf("segment variable %s outside of a list", pat.String())
+		}
+		return bindVar(string(*pat.Atom), bound, template)
+	}
+	for i, sub := range pat.List {
+		if !isSegmentVar(sub) {
+			if err := checkPattern(sub, bound, template); err != nil {
+				return err
+			}
+			continue
+		}
+		if !template && i > 0 && isSegmentVar(pat.List[i-1]) {
+			return fmt.Errorf("adjacent segment variables in %s", pat.String())
+		}
+		if err := bindVar(string(*sub.Atom), bound, template); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func bindVar(name string, bound map[string]struct{}, template bool) error {
+	if template {
+		if _, ok := bound[name]; !ok {
+			return fmt.Errorf("unbound variable %s", name)
+		}
+		return nil
+	}
+	bound[name] = struct{}{}
+	return nil
+}
+
+// Matched sub expressions by variable, segments are bound to lists
+type bindings map[string]SExp
+
+func (b bindings) clone() bindings {
+	ret := make(bindings, len(b))
+	for v, s := range b {
+		ret[v] = s
+	}
+	return ret
+}
+
+func (b bindings) bind(name string, s SExp) bool {
+	if prev, ok := b[name]; ok {
+		return prev.String() == s.String()
+	}
+	b[name] = s
+	return true
+}
+
+func match(pat SExp, s SExp, b bindings) bool {
+	if isPatternVar(pat) {
+		return b.bind(string(*pat.Atom), s)
+	}
+	if pat.Atom != nil {
+		if s.Atom == nil {
+			return false
+		}
+		if *pat.Atom == *s.Atom {
+			return true
+		}
+		l, lerr := ParseRational(string(*pat.Atom))
+		r, rerr := ParseRational(string(*s.Atom))
+		return lerr == nil && rerr == nil && l.Equal(r)
+	}
+	if s.Atom != nil {
+		return false
+	}
+	return matchList(pat.List, s.List, b)
+}
+
+// Match elements of a list, segments try the shortest runs first and
+// backtrack when the rest of the list does not match
+func matchList(pats []SExp, ss []SExp, b bindings) bool {
+	if len(pats) == 0 {
+		return len(ss) == 0
+	}
+	if !isSegmentVar(pats[0]) {
+		return len(ss) > 0 && match(pats[0], ss[0], b) && matchList(pats[1:], ss[1:], b)
+	}
+	name := string(*pats[0].Atom)
+	for k := 0; k <= len(ss); k++ {
+		try := b.clone()
+		if try.bind(name, SExp{List: append([]SExp{}, ss[:k]...)}) && matchList(pats[1:], ss[k:], try) {
+			for v, s := range try {
+				b[v] = s
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func substitute(tmpl SExp, b bindings) SExp {
+	if isPatternVar(tmpl) {
+		return b[string(*tmpl.Atom)]
+	}
+	if tmpl.Atom != nil {
+		return tmpl
+	}
+	ret := SExp{List: make([]SExp, 0, len(tmpl.List))}
+	for _, sub := range tmpl.List {
+		if isSegmentVar(sub) {
+			ret.List = append(ret.List, b[string(*sub.Atom)].List...)
+			continue
+		}
+		ret.List = append(ret.List, substitute(sub, b))
+	}
+	return ret
+}
+
+// Cheap check of the root of exp before printing it, products and sums print
+// as their head followed by their children
+func (r *PatternRule) mayMatch(exp PolyExp) bool {
+	if len(r.lhs.List) == 0 || r.lhs.List[0].Atom == nil {
+		return true
+	}
+	var children int
+	switch *r.lhs.List[0].Atom {
+	case "*":
+		if !exp.IsProduct() {
+			return false
+		}
+		children = 2 + len(exp.product().fs)
+	case "+":
+		if !exp.IsSum() {
+			return false
+		}
+		children = len(exp.sum().ps)
+	default:
+		return true
+	}
+	for _, sub := range r.lhs.List {
+		if isSegmentVar(sub) {
+			return true
+		}
+	}
+	return len(r.lhs.List) == 1+children
+}
+
+func (r *PatternRule) Apply(exp PolyExp) (PolyExp, bool, error) {
+	if !r.mayMatch(exp) {
+		return exp, false, nil
+	}
+	b := make(bindings)
+	if !match(r.lhs, exp.ToSExp(), b) {
+		return exp, false, nil
+	}
+	out := substitute(r.rhs, b)
+	var ret PolyExp
+	// parameters of exp stay parameters
+	if err := ret.ParseParams(out, exp.Params()); err != nil {
+		return PolyExp{}, false, fmt.Errorf("%s, rule %s rewrote %s into invalid expression %s", err, r.String(), exp.ToSExp().String(), out.String())
+	}
+	// matching is not a change, rewriting into the same expression does not
+	// apply the rule
+	if ret.Equal(&exp) {
+		return exp, false, nil
+	}
+	return ret, true, nil
+}
+
+// Built-in pass running a Go transformation over the whole expression, it
+// applies when the result differs structurally.  The passes of Simplify that
+// can't be written as patterns, Expand, Together, Apart and Fold, are pass
+// rules.  Traced, a pass records itself followed by the individual rewrites
+// its transformation records.
+type passRule struct {
+	name string
+	f    func(PolyExp, *Trace) (PolyExp, error)
+}
+
+// Rule of a built-in transformation, see passRule
+func NewPassRule(name string, f func(PolyExp) (PolyExp, error)) Rule {
+	return &passRule{name: name, f: func(exp PolyExp, tr *Trace) (PolyExp, error) {
+		return f(exp)
+	}}
+}
+
+// Pass rule of a transformation recording its own rewrites in tr
+func newTracedPassRule(name string, f func(PolyExp, *Trace) (PolyExp, error)) Rule {
+	return &passRule{name: name, f: f}
+}
+
+func (r *passRule) String() string {
+	return r.name
+}
+
+func (r *passRule) Apply(exp PolyExp) (PolyExp, bool, error) {
+	return r.applyTrace(exp, nil)
+}
+
+func (r *passRule) applyTrace(exp PolyExp, tr *Trace) (PolyExp, bool, error) {
+	i := tr.begin(r.name, exp)
+	ret, err := r.f(exp, tr)
+	if err != nil {
+		tr.truncate(i)
+		return PolyExp{}, false, err
+	}
+	if ret.Equal(&exp) {
+		tr.truncate(i)
+		return exp, false, nil
+	}
+	tr.end(i, ret)
+	return ret, true, nil
+}
+
+// Rule recording its own steps when traced, see passRule
+type tracedRule interface {
+	applyTrace(exp PolyExp, tr *Trace) (PolyExp, bool, error)
+}
+
+// Where in the expression a rule set tries its rules
+type Strategy int
+
+const (
+	// Rewrite children before their parents, see Rewrite
+	BottomUp Strategy = iota
+	// Rewrite parents before their children
+	TopDown
+	// Only rewrite the whole expression
+	RootOnly
+)
+
+var strategyNames = map[Strategy]string{
+	BottomUp: "bottomup",
+	TopDown:  "topdown",
+	RootOnly: "root",
+}
+
+func (s Strategy) String() string {
+	return strategyNames[s]
+}
+
+func ParseStrategy(raw string) (Strategy, error) {
+	for s, name := range strategyNames {
+		if name == raw {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid strategy %s, expected one of bottomup, topdown or root", raw)
+}
+
+const DefaultMaxPasses = 100
+
+// Rules applied together until none applies.  Each pass over the expression
+// tries the rules in order at every node visited by the strategy and the
+// first rule that applies rewrites the node.
+type RuleSet struct {
+	Name     string
+	Rules    []Rule
+	Strategy Strategy
+	// Passes before giving up on reaching a fixpoint, DefaultMaxPasses when 0
+	// and unbounded when negative, for rule sets known to terminate
+	MaxPasses int
+	// Make a single pass instead of running to a fixpoint, for rules that
+	// are not idempotent
+	Once bool
+	// Skipped by ApplyRuleSets, for optional stages such as Expand in
+	// Simplify
+	Disabled bool
+}
+
+// Apply rules until none applies, failing if that takes more than MaxPasses passes
+func (rs *RuleSet) Apply(exp PolyExp) (PolyExp, error) {
+	return rs.ApplyTrace(exp, nil)
+}
+
+// Apply recording every rule application in tr
+func (rs *RuleSet) ApplyTrace(exp PolyExp, tr *Trace) (PolyExp, error) {
+	if rs.Once {
+		ret, _, err := rs.pass(exp, tr)
+		if err != nil {
+			return PolyExp{}, fmt.Errorf("%s, applying rule set %s", err, rs.Name)
+		}
+		return ret, nil
+	}
+	maxPasses := rs.MaxPasses
+	if maxPasses == 0 {
+		maxPasses = DefaultMaxPasses
+	}
+	for i := 0; maxPasses < 0 || i < maxPasses; i++ {
+		next, changed, err := rs.pass(exp, tr)
+		if err != nil {
+			return PolyExp{}, fmt.Errorf("%s, applying rule set %s", err, rs.Name)
+		}
+		if !changed {
+			return next, nil
+		}
+		exp = next
+	}
+	return PolyExp{}, fmt.Errorf("rule set %s did not reach a fixpoint after %d passes", rs.Name, maxPasses)
+}
+
+// Apply the first rule that applies at the root of exp
+func (rs *RuleSet) applyAt(exp PolyExp, tr *Trace) (PolyExp, bool, error) {
+	for _, r := range rs.Rules {
+		if tr != nil {
+			if traced, ok := r.(tracedRule); ok {
+				ret, ok, err := traced.applyTrace(exp, tr)
+				if err != nil || ok {
+					return ret, ok, err
+				}
+				continue
+			}
+		}
+		ret, ok, err := r.Apply(exp)
+		if err != nil {
+			return PolyExp{}, false, err
+		}
+		if ok {
+			tr.record(r.String(), exp, ret)
+			return ret, true, nil
+		}
+	}
+	return exp, false, nil
+}
+
+func (rs *RuleSet) pass(exp PolyExp, tr *Trace) (PolyExp, bool, error) {
+	switch rs.Strategy {
+	case RootOnly:
+		return rs.applyAt(exp, tr)
+	case TopDown:
+		return rs.topDown(exp, tr)
+	}
+	var err error
+	ret, changed, rerr := rewrite(exp, func(node PolyExp) (PolyExp, bool) {
+		if err != nil {
+			return node, false
+		}
+		var ret PolyExp
+		var ok bool
+		ret, ok, err = rs.applyAt(node, tr)
+		return ret, ok
+	})
+	if err != nil {
+		return PolyExp{}, false, err
+	}
+	return ret, changed, rerr
+}
+
+func (rs *RuleSet) topDown(exp PolyExp, tr *Trace) (PolyExp, bool, error) {
+	node, changed, err := rs.applyAt(exp, tr)
+	if err != nil {
+		return PolyExp{}, false, err
+	}
+	children := node.e.children()
+	rebuild := false
+	for i := range children {
+		c, ok, err := rs.topDown(children[i], tr)
+		if err != nil {
+			return PolyExp{}, false, err
+		}
+		children[i] = c
+		rebuild = rebuild || ok
+	}
+	if !rebuild {
+		return node, changed, nil
+	}
+	e, err := node.e.rebuild(children)
+	if err != nil {
+		return PolyExp{}, false, fmt.Errorf("%s, failed to rebuild %s", err, node.ToSExp().String())
+	}
+	return PolyExp{e: e}, true, nil
+}
+
+// Apply rule sets in order, skipping disabled ones
+func ApplyRuleSets(exp PolyExp, sets ...RuleSet) (PolyExp, error) {
+	return ApplyRuleSetsTrace(exp, nil, sets...)
+}
+
+// Apply rule sets in order recording every rule application in tr
+func ApplyRuleSetsTrace(exp PolyExp, tr *Trace, sets ...RuleSet) (PolyExp, error) {
+	for i := range sets {
+		if sets[i].Disabled {
+			continue
+		}
+		var err error
+		exp, err = sets[i].ApplyTrace(exp, tr)
+		if err != nil {
+			return PolyExp{}, err
+		}
+	}
+	return exp, nil
+}
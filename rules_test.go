@@ -0,0 +1,197 @@
+package symdiff_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func ruleFromString(t *testing.T, raw string) Rule {
+	r, err := ParseRule(raw)
+	require.NoError(t, err)
+	return r
+}
+
+func TestParseRule(t *testing.T) {
+	r, err := ParseRule("( * 1 ?e ) => ?e")
+	require.NoError(t, err)
+	assert.Equal(t, "( * 1 ?e ) => ?e", r.String())
+	_, err = ParseRule("( + ?a ??rest 0 ) => ( + ?a ??rest )")
+	assert.NoError(t, err)
+
+	for _, raw := range []string{
+		"( * 1 ?e )",                         // no template
+		"( * 1 ?e ) => ?f",                   // unbound variable
+		"( + ??a ??b ) => 0",                 // two segments in one list
+		"??a => 0",                           // segment outside of a list
+		"( + ?a ?b ) => ( + ??a )",           // segment bound as a variable
+		"( * 1 ?e => ?e",                     // bad pattern
+		"( * 1 ?e ) => ( * ?e",               // bad template
+		"( * 1 ? ) => 0",                     // empty variable name
+		"( * 1 ?e ) => ( + ?e ??missing 1 )", // unbound segment
+	} {
+		_, err := ParseRule(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(`
+# identities
+( * 1 ?e ) => ?e
+
+  ( + ?a 0 ) => ?a
+`))
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "( + ?a 0 ) => ?a", rules[1].String())
+
+	_, err = ParseRules(strings.NewReader("( * 1 ?e ) => ?e\n( * 1 ?e ) => ?f\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestPatternRuleMatching(t *testing.T) {
+	for _, tc := range []struct {
+		rule     string
+		in       string
+		expected string
+		applies  bool
+	}{
+		{"( * 1 ?e ) => ?e", "( * 1 ( ^ x 2 ) )", "( ^ x 2 )", true},
+		{"( * 1 ?e ) => ?e", "( * 2 ( ^ x 2 ) )", "", false},
+		// numbers match numerically
+		{"( * 2/2 ?e ) => ?e", "( * 1 a )", "a", true},
+		// repeated variables match equal sub expressions
+		{"( + ?a ?a ) => ( * 2 ?a )", "( + ( ^ x 2 ) ( ^ x 2 ) )", "( * 2 ( ^ x 2 ) )", true},
+		{"( + ?a ?a ) => ( * 2 ?a )", "( + ( ^ x 2 ) ( ^ x 3 ) )", "", false},
+		// segments
+		{"( + ?a ??rest ) => ( + ??rest ?a )", "( + 1 ( ^ x 1 ) a )", "( + ( ^ x 1 ) a 1 )", true},
+		{"( + ??rest 0 ) => ( + ??rest 1 )", "( + ( ^ x 1 ) a 0 )", "( + ( ^ x 1 ) a 1 )", true},
+		{"( + ?a ??rest ?b ) => ( + ?b ??rest ?a )", "( + 1 2 )", "( + 2 1 )", true},
+		{"( + ?a ??rest ?b ) => ( + ?b ??rest ?a )", "( ^ x 1 )", "", false},
+		// separated segments take the shortest runs that match
+		{"( + ??a ( + ??b ) ??c ) => ( + ??a ??b ??c )", "( + 1 ( + ( ^ x 1 ) a ) ( + 2 3 ) )", "( + 1 ( ^ x 1 ) a ( + 2 3 ) )", true},
+		{"( + ??a ( ^ x ?n ) ??b ) => ( + ( ^ x ?n ) ??a ??b )", "( + 1 ( ^ y 1 ) ( ^ x 2 ) 3 )", "( + ( ^ x 2 ) 1 ( ^ y 1 ) 3 )", true},
+		{"( + ??a ( ^ x ?n ) ??b ) => ( + ( ^ x ?n ) ??a ??b )", "( + 1 ( ^ y 1 ) 3 )", "", false},
+		// atoms and lists don't match each other
+		{"( abs ?e ) => ?e", "a", "", false},
+		{"( ^ x ?n ) => ( ^ y ?n )", "( ^ x n )", "( ^ y n )", true},
+	} {
+		r := ruleFromString(t, tc.rule)
+		out, ok, err := r.Apply(polyFromString(t, tc.in, "a", "n"))
+		require.NoError(t, err, tc.rule)
+		assert.Equal(t, tc.applies, ok, tc.rule)
+		if tc.applies {
+			assert.Equal(t, tc.expected, out.ToSExp().String(), tc.rule)
+		}
+	}
+
+	// rewriting into an invalid expression fails
+	r := ruleFromString(t, "( * ?a ?e ) => ( * ?e ?a )")
+	_, _, err := r.Apply(polyFromString(t, "( * 2 ( ^ x 1 ) )"))
+	assert.Error(t, err)
+}
+
+func TestRuleSetStrategies(t *testing.T) {
+	identities := []Rule{
+		ruleFromString(t, "( * 1 ?e ) => ?e"),
+		ruleFromString(t, "( + ?a 0 ) => ?a"),
+	}
+	in := polyFromString(t, "( * 1 ( + ( abs ( * 1 ( + ( ^ x 1 ) 0 ) ) ) 0 ) )")
+	for _, strategy := range []Strategy{BottomUp, TopDown} {
+		rs := RuleSet{Name: "identities", Rules: identities, Strategy: strategy}
+		out, err := rs.Apply(in)
+		require.NoError(t, err)
+		assert.Equal(t, "( abs ( ^ x 1 ) )", out.ToSExp().String(), strategy.String())
+	}
+
+	rs := RuleSet{Name: "identities", Rules: identities, Strategy: RootOnly}
+	out, err := rs.Apply(in)
+	require.NoError(t, err)
+	assert.Equal(t, "( abs ( * 1 ( + ( ^ x 1 ) 0 ) ) )", out.ToSExp().String())
+
+	// once makes a single pass
+	rs = RuleSet{Name: "identities", Rules: identities, Strategy: RootOnly, Once: true}
+	out, err = rs.Apply(in)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( abs ( * 1 ( + ( ^ x 1 ) 0 ) ) ) 0 )", out.ToSExp().String())
+
+	// input is not modified
+	assert.Equal(t, "( * 1 ( + ( abs ( * 1 ( + ( ^ x 1 ) 0 ) ) ) 0 ) )", in.ToSExp().String())
+}
+
+func TestRuleSetFixpoint(t *testing.T) {
+	swap := RuleSet{
+		Name:      "swap",
+		Rules:     []Rule{ruleFromString(t, "( + ?a ?b ) => ( + ?b ?a )")},
+		Strategy:  RootOnly,
+		MaxPasses: 5,
+	}
+	_, err := swap.Apply(polyFromString(t, "( + ( ^ x 1 ) 1 )"))
+	assert.ErrorContains(t, err, "fixpoint")
+
+	// rule sets known to terminate run without a pass limit
+	flatten := RuleSet{
+		Name:      "flatten",
+		Rules:     []Rule{ruleFromString(t, "( + ??a ( + ??b ) ??c ) => ( + ??a ??b ??c )")},
+		MaxPasses: -1,
+	}
+	nested := make([]string, 0, 2*DefaultMaxPasses)
+	for i := 0; i < 2*DefaultMaxPasses; i++ {
+		nested = append(nested, fmt.Sprintf("( + ( ^ x %d ) %d )", i+1, i))
+	}
+	out, err := flatten.Apply(polyFromString(t, "( + "+strings.Join(nested, " ")+" )"))
+	require.NoError(t, err)
+	assert.Len(t, out.ToSExp().List, 4*DefaultMaxPasses+1)
+
+	// rewriting into the same expression is not a change
+	identity := ruleFromString(t, "( + ?a ?b ) => ( + ?a ?b )")
+	in := polyFromString(t, "( + ( ^ x 1 ) 1 )")
+	_, ok, err := identity.Apply(in)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	s := NewSession()
+	_, err = s.AddRule(identity.String())
+	require.NoError(t, err)
+	out, err = s.Simplify(in)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( ^ x 1 ) 1 )", out.ToSExp().String())
+
+	for _, raw := range []string{"bottomup", "topdown", "root"} {
+		s, err := ParseStrategy(raw)
+		require.NoError(t, err)
+		assert.Equal(t, raw, s.String())
+	}
+	_, err = ParseStrategy("sideways")
+	assert.Error(t, err)
+}
+
+func TestSimplifyRuleSets(t *testing.T) {
+	sets := SimplifyRuleSets()
+	var names []string
+	for _, rs := range sets {
+		names = append(names, rs.Name)
+	}
+	assert.Equal(t, []string{"Expand", "Together", "Apart", "ApplyProducts", "Flatten", "Fold", "DropZero"}, names)
+	assert.True(t, sets[0].Disabled)
+	assert.True(t, sets[1].Disabled)
+	assert.True(t, sets[2].Disabled)
+
+	// user rules run after simplification
+	poly := polyFromString(t, "( + ( * 2 ( ^ x 1 ) ) ( * 3 ( ^ x 1 ) ) ( abs ( * 1 ( ^ y 2 ) ) ) )")
+	sets = append(sets, RuleSet{
+		Name:  "abs of squares",
+		Rules: []Rule{ruleFromString(t, "( abs ( ^ ?x 2 ) ) => ( ^ ?x 2 )")},
+	})
+	out, err := ApplyRuleSets(poly, sets...)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 5 ( ^ x 1 ) ) ( ^ y 2 ) )", out.ToSExp().String())
+
+	simplified, err := Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 5 ( ^ x 1 ) ) ( abs ( ^ y 2 ) ) )", simplified.ToSExp().String())
+}
@@ -0,0 +1,243 @@
+package symdiff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Simplification configured interactively: the passes of Simplify can be
+// switched off individually and user rules are applied after them.
+// State lasts as long as the session, use WriteRules and LoadRules to keep
+// rules between sessions.
+// Results are cached until the passes or rules change.
+// Bare symbols in expressions parsed by the session are variables unless
+// declared parameters.
+type Session struct {
+	passes   []RuleSet
+	disabled map[string]bool
+	rules    RuleSet
+	order    TermOrder
+	params   []Symbol
+	cache    *Cache
+}
+
+func NewSession() *Session {
+	passes := SimplifyRuleSets()
+	disabled := make(map[string]bool)
+	for _, rs := range passes {
+		disabled[rs.Name] = rs.Disabled
+	}
+	return &Session{
+		passes:   passes,
+		disabled: disabled,
+		rules: RuleSet{
+			Name:     "user",
+			Strategy: BottomUp,
+		},
+		cache: NewCache(DefaultCacheSize),
+	}
+}
+
+// Status of a simplification pass
+type PassStatus struct {
+	Name    string
+	Enabled bool
+}
+
+// Passes of Simplify in the order they run
+func (s *Session) Passes() []PassStatus {
+	ret := make([]PassStatus, len(s.passes))
+	for i, rs := range s.passes {
+		ret[i] = PassStatus{Name: rs.Name, Enabled: !s.disabled[rs.Name]}
+	}
+	return ret
+}
+
+// Pass by case insensitive name
+func (s *Session) pass(name string) (*RuleSet, error) {
+	for i := range s.passes {
+		if strings.EqualFold(s.passes[i].Name, name) {
+			return &s.passes[i], nil
+		}
+	}
+	names := make([]string, len(s.passes))
+	for i, rs := range s.passes {
+		names[i] = rs.Name
+	}
+	return nil, fmt.Errorf("no pass %s, expected one of %s", name, strings.Join(names, ", "))
+}
+
+func (s *Session) Enable(name string) error {
+	return s.setEnabled(name, true)
+}
+
+func (s *Session) Disable(name string) error {
+	return s.setEnabled(name, false)
+}
+
+func (s *Session) setEnabled(name string, enabled bool) error {
+	rs, err := s.pass(name)
+	if err != nil {
+		return err
+	}
+	s.disabled[rs.Name] = !enabled
+	s.cache.Clear()
+	return nil
+}
+
+// Run a single pass whether or not it is enabled
+func (s *Session) RunPass(name string, exp PolyExp) (PolyExp, error) {
+	rs, err := s.pass(name)
+	if err != nil {
+		return PolyExp{}, err
+	}
+	return rs.Apply(exp)
+}
+
+// Parse and add a rule applied after the passes, see ParseRule
+func (s *Session) AddRule(raw string) (Rule, error) {
+	r, err := ParseRule(raw)
+	if err != nil {
+		return nil, err
+	}
+	s.rules.Rules = append(s.rules.Rules, r)
+	s.cache.Clear()
+	return r, nil
+}
+
+// Remove the i-th user rule counting from 0
+func (s *Session) RemoveRule(i int) error {
+	if i < 0 || i >= len(s.rules.Rules) {
+		return fmt.Errorf("no rule %d, there are %d rules", i, len(s.rules.Rules))
+	}
+	s.rules.Rules = append(s.rules.Rules[:i:i], s.rules.Rules[i+1:]...)
+	s.cache.Clear()
+	return nil
+}
+
+func (s *Session) Rules() []Rule {
+	return append([]Rule{}, s.rules.Rules...)
+}
+
+// Set where user rules are applied
+func (s *Session) SetStrategy(strategy Strategy) {
+	s.rules.Strategy = strategy
+	s.cache.Clear()
+}
+
+func (s *Session) Strategy() Strategy {
+	return s.rules.Strategy
+}
+
+// Set the order of terms after folding
+func (s *Session) SetOrder(order TermOrder) {
+	rs, _ := s.pass("Fold")
+	rs.Rules = foldRuleSet(order).Rules
+	s.order = order
+	s.cache.Clear()
+}
+
+func (s *Session) Order() TermOrder {
+	return s.order
+}
+
+// Add rules from a rules file, see ParseRules
+func (s *Session) LoadRules(in io.Reader) error {
+	rules, err := ParseRules(in)
+	if err != nil {
+		return err
+	}
+	s.rules.Rules = append(s.rules.Rules, rules...)
+	s.cache.Clear()
+	return nil
+}
+
+// Write user rules in the rules file format
+func (s *Session) WriteRules(out io.Writer) error {
+	for _, r := range s.rules.Rules {
+		if _, err := fmt.Fprintln(out, r.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Declare symbols parameters of the expressions parsed from now on
+func (s *Session) Declare(params ...Symbol) error {
+	for _, a := range params {
+		if !IsSymbol(string(a)) {
+			return fmt.Errorf("invalid parameter %s, parameters are symbols", a)
+		}
+	}
+	for _, a := range params {
+		if !s.isParam(a) {
+			s.params = append(s.params, a)
+		}
+	}
+	return nil
+}
+
+// Declared parameters in the order of declaration
+func (s *Session) Params() []Symbol {
+	return append([]Symbol{}, s.params...)
+}
+
+func (s *Session) isParam(a Symbol) bool {
+	for _, p := range s.params {
+		if p == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse an expression with the declared parameters, see ParseParams
+func (s *Session) Parse(raw string) (PolyExp, error) {
+	var sexp SExp
+	if err := sexp.Parse(raw); err != nil {
+		return PolyExp{}, err
+	}
+	var ret PolyExp
+	if err := ret.ParseParams(sexp, s.params); err != nil {
+		return PolyExp{}, err
+	}
+	return ret, nil
+}
+
+// Run the enabled passes followed by the user rules
+func (s *Session) Simplify(exp PolyExp) (PolyExp, error) {
+	ret, err := s.cache.memo("session", "", exp, func() (*PolyExp, error) {
+		ret, err := s.SimplifyTrace(exp, nil)
+		return &ret, err
+	})
+	if err != nil {
+		return PolyExp{}, err
+	}
+	return *ret, nil
+}
+
+// Differentiate through the session cache
+func (s *Session) Differentiate(v Symbol, exp PolyExp) (*PolyExp, error) {
+	return s.cache.Differentiate(v, exp)
+}
+
+// Statistics of the session cache since the passes or rules last changed
+func (s *Session) CacheStats() CacheStats {
+	return s.cache.Stats()
+}
+
+// Simplify recording every rule application in tr
+func (s *Session) SimplifyTrace(exp PolyExp, tr *Trace) (PolyExp, error) {
+	sets := make([]RuleSet, 0, len(s.passes)+1)
+	for _, rs := range s.passes {
+		if !s.disabled[rs.Name] {
+			rs.Disabled = false
+			sets = append(sets, rs)
+		}
+	}
+	if len(s.rules.Rules) > 0 {
+		sets = append(sets, s.rules)
+	}
+	return ApplyRuleSetsTrace(exp, tr, sets...)
+}
@@ -0,0 +1,167 @@
+package symdiff_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestSessionPasses(t *testing.T) {
+	s := NewSession()
+	assert.Equal(t, []PassStatus{
+		{Name: "Expand", Enabled: false},
+		{Name: "Together", Enabled: false},
+		{Name: "Apart", Enabled: false},
+		{Name: "ApplyProducts", Enabled: true},
+		{Name: "Flatten", Enabled: true},
+		{Name: "Fold", Enabled: true},
+		{Name: "DropZero", Enabled: true},
+	}, s.Passes())
+
+	poly := polyFromString(t, "( + ( * 2 ( + ( ^ x 1 ) 0 ) ) ( ^ x 1 ) )")
+	out, err := s.Simplify(poly)
+	require.NoError(t, err)
+	expected, err := Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, expected.ToSExp().String(), out.ToSExp().String())
+
+	// without folding coefficients are distributed and sums flattened only
+	require.NoError(t, s.Disable("fold"))
+	assert.False(t, s.Passes()[5].Enabled)
+	out, err = s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 2 ( ^ x 1 ) ) 0 ( ^ x 1 ) )", out.ToSExp().String())
+
+	require.NoError(t, s.Enable("Fold"))
+	out, err = s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( * 3 ( ^ x 1 ) )", out.ToSExp().String())
+
+	assert.Error(t, s.Disable("reticulate"))
+
+	// single passes run even when disabled
+	require.NoError(t, s.Disable("ApplyProducts"))
+	out, err = s.RunPass("applyproducts", poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( + ( * 2 ( ^ x 1 ) ) 0 ) ( ^ x 1 ) )", out.ToSExp().String())
+	_, err = s.RunPass("reticulate", poly)
+	assert.Error(t, err)
+}
+
+func TestSessionExpand(t *testing.T) {
+	s := NewSession()
+	poly := polyFromString(t, "( * 1 ( + ( ^ x 1 ) 1 ) ( + ( ^ x 1 ) -1 ) )")
+	out, err := s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( * 1 ( + ( ^ x 1 ) 1 ) ( + ( ^ x 1 ) -1 ) )", out.ToSExp().String())
+
+	require.NoError(t, s.Enable("expand"))
+	out, err = s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( ^ x 2 ) -1 )", out.ToSExp().String())
+}
+
+func TestSessionRules(t *testing.T) {
+	s := NewSession()
+	_, err := s.AddRule("( abs ( ^ ?x 2 ) ) => ( ^ ?x 2 )")
+	require.NoError(t, err)
+	_, err = s.AddRule("( * 1 ?e ) => ?f")
+	assert.Error(t, err)
+	require.Len(t, s.Rules(), 1)
+
+	poly := polyFromString(t, "( + ( abs ( ^ y 2 ) ) ( abs ( ^ y 2 ) ) )")
+	out, err := s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( * 2 ( ^ y 2 ) )", out.ToSExp().String())
+
+	s.SetStrategy(RootOnly)
+	assert.Equal(t, RootOnly, s.Strategy())
+	out, err = s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( * 2 ( abs ( ^ y 2 ) ) )", out.ToSExp().String())
+
+	// rules round trip through the rules file format
+	var buf bytes.Buffer
+	require.NoError(t, s.WriteRules(&buf))
+	loaded := NewSession()
+	require.NoError(t, loaded.LoadRules(strings.NewReader(buf.String())))
+	assert.Equal(t, s.Rules(), loaded.Rules())
+	assert.Error(t, loaded.LoadRules(strings.NewReader("( * 1 ?e )")))
+
+	require.NoError(t, s.RemoveRule(0))
+	assert.Empty(t, s.Rules())
+	assert.Error(t, s.RemoveRule(0))
+	assert.Len(t, loaded.Rules(), 1)
+}
+
+func TestSessionCache(t *testing.T) {
+	s := NewSession()
+	poly := polyFromString(t, "( + ( ^ x 2 ) ( ^ x 2 ) 0 )")
+	first, err := s.Simplify(poly)
+	require.NoError(t, err)
+	_, err = s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.CacheStats().Hits)
+
+	// changing rules invalidates cached results
+	_, err = s.AddRule("( * 2 ?x ) => ( + ?x ?x )")
+	require.NoError(t, err)
+	assert.Equal(t, CacheStats{}, s.CacheStats())
+	second, err := s.Simplify(poly)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ToSExp().String(), second.ToSExp().String())
+
+	require.NoError(t, s.Disable("fold"))
+	assert.Equal(t, 0, s.CacheStats().Size)
+}
+
+func TestSessionOrder(t *testing.T) {
+	s := NewSession()
+	assert.Equal(t, Ascending, s.Order())
+	poly := polyFromString(t, "( + ( * 2 ( ^ x 1 ) ) ( ^ x 3 ) ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) 5 )")
+	out, err := s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( * 2 ( ^ x 1 ) ) ( ^ x 3 ) 5 )", out.ToSExp().String())
+
+	s.SetOrder(GradedLex)
+	assert.Equal(t, GradedLex, s.Order())
+	out, err = s.Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( ^ x 3 ) ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( * 2 ( ^ x 1 ) ) 5 )", out.ToSExp().String())
+
+	// other sessions keep the default
+	out, err = NewSession().Simplify(poly)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 1 ( ^ x 1 ) ( ^ y 1 ) ) ( * 2 ( ^ x 1 ) ) ( ^ x 3 ) 5 )", out.ToSExp().String())
+}
+
+func TestSessionParams(t *testing.T) {
+	s := NewSession()
+	// undeclared symbols are variables
+	poly, err := s.Parse("( + ( * a x ) 1 )")
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 1 ( ^ a 1 ) ( ^ x 1 ) ) 1 )", poly.ToSExp().String())
+	_, err = s.Parse("( ^ x n )")
+	assert.Error(t, err)
+
+	require.NoError(t, s.Declare("a", "n", "a"))
+	assert.Equal(t, []Symbol{"a", "n"}, s.Params())
+	assert.Error(t, s.Declare("+"))
+	poly, err = s.Parse("( + ( * a x ) ( ^ x n ) )")
+	require.NoError(t, err)
+	d, err := s.Differentiate("x", poly)
+	require.NoError(t, err)
+	out, err := s.Simplify(*d)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * n ( ^ x ( + n -1 ) ) ) a )", out.ToSExp().String())
+
+	// parameters are constant
+	poly, err = s.Parse("( + a 1 )")
+	require.NoError(t, err)
+	_, err = s.Differentiate("a", poly)
+	assert.Error(t, err)
+}
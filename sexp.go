@@ -77,7 +77,7 @@ func (s *SExp) Equal(t *SExp) bool {
 
 // Parse an S-Expression out of a raw string
 // Support for modern notation for succinct representation of lists with more than one element
-// Limited atom support -- only alphanumeric strings and registered exceptions are allowed as atoms
+// Limited atom support -- only alphanumeric strings, rationals like -3/2 and registered exceptions are allowed as atoms
 /*
  "( A ( B 0) ())" parses to:
 
@@ -141,13 +141,15 @@ func (s *SExp) Parse(raw string) (err error) {
 }
 
 func isAtomChar(r rune) bool {
-	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-'
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '/'
 }
 
 func isAtom(raw string) bool {
 	if _, special := SpecialAtoms[raw]; special {
 		return true
 	}
+	// pattern variables ?x and ??xs of rewrite rules, see rules.go
+	raw = strings.TrimPrefix(strings.TrimPrefix(raw, PatternVarPrefix), PatternVarPrefix)
 	// excluding special atoms, atoms are contiguous alphanumeric strings excluding the empty string
 	if len(raw) == 0 {
 		return false
@@ -7,34 +7,179 @@ import (
 /*
 simplification logic
 - de nest all sums into one flat sum expression
-- distribute products through all poly, sum, products and constants, only keep around monomials
+- distribute coefficients over sums and gather nested products into one coefficient
 - add together all monomials of the same term
 - normalizze ( ^ x 0) to constant 1
 - drop zero constants
+- simplify bases of powers and factors of products recursively
+
+products of sums are only multiplied out by the optional Expand stage, see
+SimplifyExpanded
 */
 func Simplify(poly PolyExp) (*PolyExp, error) {
-	// Distribute
-	// All products are distributed through to constant or monomial terms
-	poly1, err := ApplyProducts(1, poly)
+	ret, err := ApplyRuleSets(poly, simplifyRules...)
 	if err != nil {
 		return nil, err
 	}
+	return &ret, nil
+}
+
+// Simplify with the Expand stage enabled, products and powers of sums are
+// multiplied out before simplifying
+func SimplifyExpanded(poly PolyExp) (*PolyExp, error) {
+	sets := SimplifyRuleSets()
+	for i := range sets {
+		if sets[i].Name == "Expand" {
+			sets[i].Disabled = false
+		}
+	}
+	ret, err := ApplyRuleSets(poly, sets...)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// Simplification steps of Simplify as rule sets applied in order, see
+// simplifyRules.  Optional stages are disabled.
+func SimplifyRuleSets() []RuleSet {
+	return append([]RuleSet{}, simplifyRules...)
+}
+
+// Simplification steps of Simplify with terms folded in the given order
+func SimplifyRuleSetsOrder(order TermOrder) []RuleSet {
+	sets := SimplifyRuleSets()
+	for i := range sets {
+		if sets[i].Name == "Fold" {
+			sets[i].Rules = foldRuleSet(order).Rules
+		}
+	}
+	return sets
+}
+
+// Distributing coefficients, gathering nested products, flattening sums and
+// dropping unit factors, zero products and zero terms are pattern rules.
+// Patterns match a fixed shape and can't do arithmetic, so folding any number
+// of like terms into one is the built-in Fold pass, as are the optional
+// Expand, Together and Apart stages.
+var simplifyRules []RuleSet
 
-	// Flatten
-	// All terms are flattened to one sum
-	// Add in zero term if there is only one
-	terms := Flatten(*poly1)
+func init() {
+	simplifyRules = []RuleSet{
+		// Expand
+		// Products and powers of sums are multiplied out, optional
+		{
+			Name:     "Expand",
+			Strategy: RootOnly,
+			Once:     true,
+			Disabled: true,
+			Rules: []Rule{NewPassRule("Expand", func(poly PolyExp) (PolyExp, error) {
+				ret, err := Expand(poly)
+				if err != nil {
+					return PolyExp{}, err
+				}
+				return *ret, nil
+			})},
+		},
+		// Together
+		// Rational functions are combined into one quotient, or term by term
+		// when the whole expression is not one, optional
+		{
+			Name:     "Together",
+			Strategy: RootOnly,
+			Once:     true,
+			Disabled: true,
+			Rules: []Rule{NewPassRule("Together", func(poly PolyExp) (PolyExp, error) {
+				if ret, err := Together(poly); err == nil {
+					return *ret, nil
+				}
+				ret, err := Cancel(poly)
+				if err != nil {
+					return PolyExp{}, err
+				}
+				return *ret, nil
+			})},
+		},
+		// Apart
+		// Rational functions are decomposed into partial fractions, optional
+		{
+			Name:     "Apart",
+			Strategy: RootOnly,
+			Once:     true,
+			Disabled: true,
+			Rules: []Rule{NewPassRule("Apart", func(poly PolyExp) (PolyExp, error) {
+				if ret, err := Apart(poly); err == nil {
+					return *ret, nil
+				}
+				return poly, nil
+			})},
+		},
+		// Distribute
+		// Coefficients are distributed over sums and nested products become
+		// products of coefficients, ( * a ( * b ( ^ x 1 ) ) ) ==> ( * ( * a b ) ( ^ x 1 ) )
+		{
+			Name: "ApplyProducts",
+			// a coefficient distributed over a sum is distributed over the
+			// rest of the sum in the same pass
+			Strategy: TopDown,
+			// every rewrite removes a product or moves a sum or product up
+			// out of the right of one
+			MaxPasses: -1,
+			Rules: []Rule{
+				mustParseRule("( * 1 ?e ) => ?e"),
+				mustParseRule("( * ?c 1 ) => ?c"),
+				mustParseRule("( * 0 ?e ) => 0"),
+				mustParseRule("( * ?c 0 ) => 0"),
+				mustParseRule("( * ?c ( + ?a ?b ) ) => ( + ( * ?c ?a ) ( * ?c ?b ) )"),
+				mustParseRule("( * ?c ( + ?a ?b ?d ??rest ) ) => ( + ( * ?c ?a ) ( * ?c ( + ?b ?d ??rest ) ) )"),
+				mustParseRule("( * ?c ( * ?d ??fs ) ) => ( * ( * ?c ?d ) ??fs )"),
+			},
+		},
+		// Flatten
+		// Nested sums are flattened into their parent sum
+		{
+			Name:      "Flatten",
+			Strategy:  BottomUp,
+			MaxPasses: -1,
+			Rules: []Rule{
+				mustParseRule("( + ??a ( + ??b ) ??c ) => ( + ??a ??b ??c )"),
+			},
+		},
+		// Fold
+		// All terms of the same exponent and symbol are added together, the
+		// constant term is always last
+		foldRuleSet(Ascending),
+		// Drop
+		// Zero constant is removed from top level if there are any other terms
+		{
+			Name:     "DropZero",
+			Strategy: RootOnly,
+			Rules: []Rule{
+				mustParseRule("( + ?a 0 ) => ?a"),
+				mustParseRule("( + ?a ?b ??rest 0 ) => ( + ?a ?b ??rest )"),
+			},
+		},
+	}
+}
 
-	// Fold
-	// All terms of the same exponent and symbol are added together
-	terms = Fold(terms)
-	// Drop
-	// Zero constant is removed from top level if there are any other terms
-	terms = DropZero(terms)
+// Fold pass with terms in the given order
+func foldRuleSet(order TermOrder) RuleSet {
+	return RuleSet{
+		Name:     "Fold",
+		Strategy: RootOnly,
+		Once:     true,
+		Rules: []Rule{newTracedPassRule("Fold", func(poly PolyExp, tr *Trace) (PolyExp, error) {
+			return *Join(foldOrder(Flatten(poly), order, tr)), nil
+		})},
+	}
+}
 
-	// Wrap terms into one flat sum
-	// Noop if just one term
-	return Join(terms), nil
+func mustParseRule(raw string) Rule {
+	r, err := ParseRule(raw)
+	if err != nil {
+		panic(err)
+	}
+	return r
 }
 
 func DropZero(polys []PolyExp) []PolyExp {
@@ -44,7 +189,7 @@ func DropZero(polys []PolyExp) []PolyExp {
 	}
 	nonzero := make([]PolyExp, 0, len(polys))
 	for _, poly := range polys {
-		if poly.IsConstant() && poly.c.c == 0 {
+		if poly.IsConstant() && poly.constant().c.IsZero() {
 			continue
 		}
 		nonzero = append(nonzero, poly)
@@ -56,40 +201,129 @@ func DropZero(polys []PolyExp) []PolyExp {
 // Skips sums and products with non-monomial right terms.  To do a full
 // reduction into component monomials this should be applied after
 // ApplyProducts and Flatten.
+// Symbolic exponents are simplified and monomials are only combined when
+// their simplified exponents are identical.  Coefficients are expanded into
+// products of parameters and grouped per monomial, i.e.
+// ( + ( * a ( ^ x 2 ) ) ( * 2 ( * b ( ^ x 2 ) ) ) ) ==> ( * ( + a ( * 2 b ) ) ( ^ x 2 ) )
+// Powers of expressions, absolute values, signs and piecewise expressions are
+// combined like monomials when their simplified forms match.  Products of several factors have their factors
+// simplified but are otherwise untransformed.
+// Terms are in Ascending order, see FoldOrder.
 func Fold(polys []PolyExp) []PolyExp {
-	coefficients := make(map[Symbol]map[int]int) // ( * a ( ^ x n ) ) ==> map[x]->map[n]->a
-	constantCoeff := 0
-	addCoeff := func(n, a int, sym Symbol) {
+	return FoldOrder(polys, Ascending)
+}
+
+// Fold with monomials in the given order, see TermOrder
+func FoldOrder(polys []PolyExp, order TermOrder) []PolyExp {
+	return foldOrder(polys, order, nil)
+}
+
+// Fold recording a fold step for every group of several like terms added
+// together
+func foldOrder(polys []PolyExp, order TermOrder, tr *Trace) []PolyExp {
+	coefficients := make(map[Symbol]map[string]coefficient) // ( * a ( ^ x n ) ) ==> map[x]->map[key(n)]->a
+	exponents := make(map[string]MonomialExp)               // key(n) ==> ( ^ x n ) with simplified n
+	compoundCoeffs := make(map[string]coefficient)          // ( * a ( ^ f q ) ) ==> map[( ^ f q )]->a
+	compounds := make(map[string]PolyExp)
+	constantCoeff := make(coefficient)
+	// input terms added into each group, only kept when tracing
+	sources := make(map[string][]int)
+	var current int
+	source := func(group string) {
+		if tr == nil {
+			return
+		}
+		s := sources[group]
+		if len(s) > 0 && s[len(s)-1] == current {
+			return
+		}
+		sources[group] = append(s, current)
+	}
+	folded := func(group string, ret []PolyExp) {
+		if len(sources[group]) < 2 {
+			return
+		}
+		before := make([]PolyExp, len(sources[group]))
+		for i, j := range sources[group] {
+			before[i] = polys[j]
+		}
+		after := Zero()
+		if len(ret) > 0 {
+			after = *Join(ret)
+		}
+		tr.record("fold", *Join(before), after)
+	}
+	addCoeff := func(mon MonomialExp, a []coeffTerm) {
+		mon = normalizeExponent(mon)
+		if mon.e == nil && mon.n.IsZero() {
+			source("")
+			constantCoeff.add(a)
+			return
+		}
+		key := exponentKey(mon)
+		source("^" + string(mon.x) + key)
+		exponents[key] = mon
+		sym := mon.x
 		if _, ok := coefficients[sym]; !ok {
-			coefficients[sym] = make(map[int]int)
+			coefficients[sym] = make(map[string]coefficient)
 		}
-		if _, ok := coefficients[sym][n]; !ok {
-			coefficients[sym][n] = 0
+		if _, ok := coefficients[sym][key]; !ok {
+			coefficients[sym][key] = make(coefficient)
 		}
-		coefficients[sym][n] += a
+		coefficients[sym][key].add(a)
 	}
-
 	terms := make([]PolyExp, 0)
-	for _, poly := range polys {
-		if poly.IsSum() {
-			terms = append(terms, poly) // untransformed terms
-		} else if poly.IsProduct() {
-			if poly.p.r.IsMon() {
-				a := poly.p.l.c
-				sym := poly.p.r.m.x
-				n := poly.p.r.m.n
-				addCoeff(n, a, sym)
-			} else if poly.p.r.IsConstant() {
-				a := poly.p.l.c * poly.p.r.c.c
-				constantCoeff += a
-			} else {
-				terms = append(terms, poly)
+	// Add a * poly returning false if poly can't be folded
+	var addTerm func(a []coeffTerm, poly PolyExp) bool
+	addTerm = func(a []coeffTerm, poly PolyExp) bool {
+		if poly.IsMon() {
+			addCoeff(*poly.mon(), a)
+		} else if poly.isCoefficient() { // constants, parameters and their sums and products
+			source("")
+			constantCoeff.add(multiplyCoefficients(a, expandCoefficient(poly)))
+		} else if poly.isCompound() {
+			normalized := normalizeCompound(poly)
+			if normalized.IsSum() {
+				// already simplified, terms that can't be folded are kept scaled
+				for _, p := range normalized.sum().ps {
+					if !addTerm(a, p) {
+						c := make(coefficient)
+						c.add(a)
+						terms = append(terms, c.times(p)...)
+					}
+				}
+				return true
+			}
+			if !normalized.isCompound() {
+				return addTerm(a, normalized)
+			}
+			key := normalized.ToSExp().String()
+			if _, ok := compoundCoeffs[key]; !ok {
+				compoundCoeffs[key] = make(coefficient)
+			}
+			compounds[key] = normalized
+			compoundCoeffs[key].add(a)
+			source(key)
+		} else if poly.IsProduct() && len(poly.product().fs) == 0 && (poly.product().r.IsMon() || poly.product().r.isCompound()) {
+			return addTerm(multiplyCoefficients(a, expandCoefficient(*poly.product().l)), *poly.product().r)
+		} else {
+			return false
+		}
+		return true
+	}
+
+	for i, poly := range polys {
+		current = i
+		if addTerm([]coeffTerm{{k: Integer(1)}}, poly) {
+			continue
+		}
+		if poly.IsProduct() && len(poly.product().fs) > 0 {
+			poly = simplifyFactors(*poly.product())
+			if addTerm([]coeffTerm{{k: Integer(1)}}, poly) {
+				continue
 			}
-		} else if poly.IsMon() {
-			addCoeff(poly.m.n, 1, poly.m.x)
-		} else { // constant case
-			constantCoeff += poly.c.c
 		}
+		terms = append(terms, poly) // untransformed terms
 	}
 	syms := make([]string, 0)
 	for sym := range coefficients {
@@ -97,61 +331,276 @@ func Fold(polys []PolyExp) []PolyExp {
 	}
 	sort.Strings(syms)
 
+	// symbolic powers follow all numeric powers in the textbook orders
+	var symbolic []PolyExp
 	for _, s := range syms {
 		sym := Symbol(s)
-		powers := make([]int, 0)
-		for power := range coefficients[sym] {
-			powers = append(powers, power)
+		// numeric powers ascending followed by symbolic powers
+		powers := make([]MonomialExp, 0)
+		for key := range coefficients[sym] {
+			powers = append(powers, exponents[key])
 		}
-		sort.Ints(powers)
-		for _, power := range powers {
-			a := coefficients[sym][power]
-
-			if power == 0 {
-				constantCoeff += a
-				continue
+		sort.Slice(powers, func(i, j int) bool {
+			if powers[i].e == nil && powers[j].e == nil {
+				if order == Descending {
+					return powers[j].n.Less(powers[i].n)
+				}
+				return powers[i].n.Less(powers[j].n)
 			}
-
-			m := MonomialExp{
-				x: sym,
-				n: power,
+			if powers[i].e == nil || powers[j].e == nil {
+				return powers[i].e == nil
 			}
-			if a == 1 {
-				terms = append(terms, PolyExp{m: &m})
-			} else {
-				mon := PolyExp{
-					p: &ProductExp{
-						l: &ConstantExp{
-							c: a,
-						},
-						r: &PolyExp{
-							m: &m,
-						},
-					},
-				}
-				terms = append(terms, mon)
+			return exponentKey(powers[i]) < exponentKey(powers[j])
+		})
+		for i := range powers {
+			m := powers[i]
+			ret := coefficients[sym][exponentKey(m)].times(PolyExp{e: &m})
+			folded("^"+s+exponentKey(m), ret)
+			if order.monomial() && m.e != nil {
+				symbolic = append(symbolic, ret...)
+				continue
 			}
+			terms = append(terms, ret...)
 		}
 	}
-	terms = append(terms, PolyExp{c: &ConstantExp{c: constantCoeff}})
+	if order.monomial() {
+		terms = orderMonomials(terms, order)
+	}
+	terms = append(terms, symbolic...)
+
+	keys := make([]string, 0)
+	for key := range compoundCoeffs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ret := compoundCoeffs[key].times(compounds[key])
+		folded(key, ret)
+		terms = append(terms, ret...)
+	}
+
+	// Parameter terms of the constant coefficient are kept as top level terms
+	constant := Integer(0)
+	var constantTerms []PolyExp
+	for _, a := range constantCoeff.terms() {
+		if a.IsConstant() {
+			constant = a.constant().c
+			continue
+		}
+		constantTerms = append(constantTerms, a)
+	}
+	constantTerms = append(constantTerms, PolyExp{e: &ConstantExp{c: constant}})
+	folded("", constantTerms)
+	terms = append(terms, constantTerms...)
 
 	return terms
 }
 
-func Flatten(poly PolyExp) []PolyExp {
-	if poly.IsConstant() || poly.IsMon() {
-		return []PolyExp{poly}
+// Untransformed terms that are not monomials followed by monomials and
+// products of monomials in the given textbook order
+func orderMonomials(terms []PolyExp, order TermOrder) []PolyExp {
+	type monomial struct {
+		t       PolyExp
+		degrees map[Symbol]Rational
 	}
+	ret := make([]PolyExp, 0, len(terms))
+	var monomials []monomial
+	for _, t := range terms {
+		if degrees, ok := termDegrees(t); ok {
+			monomials = append(monomials, monomial{t: t, degrees: degrees})
+			continue
+		}
+		ret = append(ret, t)
+	}
+	sort.SliceStable(monomials, func(i, j int) bool {
+		return order.less(monomials[i].degrees, monomials[j].degrees)
+	})
+	for _, m := range monomials {
+		ret = append(ret, m.t)
+	}
+	return ret
+}
 
-	// Flatten does not recurse over products
-	// Its intended use is over polynomials that have already distributed all products
-	if poly.IsProduct() {
+// Simplify symbolic exponent, collapsing it to a rational exponent when possible
+func normalizeExponent(mon MonomialExp) MonomialExp {
+	if mon.e == nil {
+		return mon
+	}
+	e, err := Simplify(*mon.e)
+	if err != nil {
+		return mon
+	}
+	if e.IsConstant() {
+		return MonomialExp{x: mon.x, n: e.constant().c}
+	}
+	return MonomialExp{x: mon.x, e: e}
+}
+
+// Key identifying monomials with provably equal exponents
+// Invariant: monomial exponent is normalized
+func exponentKey(mon MonomialExp) string {
+	e := mon.Exponent()
+	return string(mon.x) + " " + e.ToSExp().String()
+}
+
+// Powers, absolute values, signs and piecewise expressions are folded as a
+// whole by their simplified form
+func (p *PolyExp) isCompound() bool {
+	return p.IsPower() || p.IsAbs() || p.IsSign() || p.IsPiecewise()
+}
+
+// Invariant: poly.isCompound()
+func normalizeCompound(poly PolyExp) PolyExp {
+	switch {
+	case poly.IsPower():
+		return normalizePower(*poly.power())
+	case poly.IsAbs():
+		return normalizeAbs(*poly.abs())
+	case poly.IsSign():
+		return normalizeSign(*poly.sign())
+	}
+	return normalizePiecewise(*poly.piecewise())
+}
+
+// Simplify the base of a power and collapse powers that are not needed
+//   - ( ^ f 0 ) ==> 1
+//   - ( ^ c n ) ==> c^n for constant c and integer n
+//   - ( ^ ( ^ x k ) q ) ==> ( ^ x kq ) when k is 1 or q is an integer
+//   - ( ^ f 1 ) ==> f when f is not a sum
+//
+// (x^2)^1/2 is |x| and is kept as a power
+func normalizePower(pow PowerExp) PolyExp {
+	if pow.n.IsZero() {
+		return PolyExp{e: &ConstantExp{c: Integer(1)}}
+	}
+	b := *pow.b
+	if simplified, err := Simplify(b); err == nil {
+		b = *simplified
+	}
+	// Constant powers too large to fold stay symbolic, see MaxPowBits
+	if b.IsConstant() && pow.n.IsInt() {
+		if c, err := b.constant().c.Pow(pow.n.Int()); err == nil {
+			return PolyExp{e: &ConstantExp{c: c}}
+		}
+	}
+	if b.IsMon() && b.mon().e == nil && (pow.n.IsInteger() || b.mon().n.Equal(Integer(1))) {
+		return PolyExp{e: &MonomialExp{x: b.mon().x, n: b.mon().n.Mul(pow.n)}}
+	}
+	if pow.n.Equal(Integer(1)) && !b.IsSum() {
+		return b
+	}
+	return PolyExp{e: &PowerExp{b: &b, n: pow.n}}
+}
+
+func simplified(poly PolyExp) PolyExp {
+	if s, err := Simplify(poly); err == nil {
+		return *s
+	}
+	return poly
+}
+
+// Simplify argument, absolute values of constants are evaluated
+func normalizeAbs(abs AbsExp) PolyExp {
+	e := simplified(*abs.e)
+	if e.IsConstant() {
+		c := e.constant().c
+		if c.Sign() < 0 {
+			c = c.Neg()
+		}
+		return PolyExp{e: &ConstantExp{c: c}}
+	}
+	return PolyExp{e: &AbsExp{e: &e}}
+}
+
+// Simplify argument, signs of constants are evaluated
+func normalizeSign(sgn SignExp) PolyExp {
+	e := simplified(*sgn.e)
+	if e.IsConstant() {
+		return PolyExp{e: &ConstantExp{c: Integer(e.constant().c.Sign())}}
+	}
+	return PolyExp{e: &SignExp{e: &e}}
+}
+
+// Simplify conditions and pieces.  Conditions between constants are decided,
+// pieces that can never apply are dropped and a piece that always applies
+// ends the piecewise expression, replacing it entirely if it is the first.
+func normalizePiecewise(pw PiecewiseExp) PolyExp {
+	ret := PiecewiseExp{}
+	for i := range pw.cs {
+		c := pw.cs[i]
+		if !c.IsElse() {
+			l, r := simplified(*c.l), simplified(*c.r)
+			c = Condition{op: c.op, l: &l, r: &r}
+			if l.IsConstant() && r.IsConstant() {
+				if !c.holds(l.constant().c.Float64(), r.constant().c.Float64()) {
+					continue
+				}
+				c = Condition{op: ElseKeyWord}
+			}
+		}
+		ret.cs = append(ret.cs, c)
+		ret.es = append(ret.es, simplified(pw.es[i]))
+		if c.IsElse() {
+			break
+		}
+	}
+	if len(ret.cs) == 0 {
+		// undefined everywhere, keep the pieces around
+		return PolyExp{e: &pw}
+	}
+	if ret.cs[0].IsElse() {
+		return ret.es[0]
+	}
+	return PolyExp{e: &ret}
+}
+
+// Simplify each factor of a product of several factors, factors that
+// simplify to coefficients are multiplied into the coefficient
+func simplifyFactors(prod ProductExp) PolyExp {
+	l, factors := prod.Term()
+	coeff := make(coefficient)
+	coeff.add(expandCoefficient(l))
+	rest := make([]PolyExp, 0, len(factors))
+	for _, f := range factors {
+		if simplified, err := Simplify(f); err == nil {
+			f = *simplified
+		}
+		if f.isCoefficient() {
+			product := make(coefficient)
+			for _, t := range coeff {
+				product.add(multiplyCoefficients([]coeffTerm{t}, expandCoefficient(f)))
+			}
+			coeff = product
+			continue
+		}
+		rest = append(rest, f)
+	}
+	a := coeff.terms()
+	if len(a) == 0 {
+		return Zero()
+	}
+	if len(rest) == 0 {
+		return *Join(a)
+	}
+	return PolyExp{
+		e: &ProductExp{
+			l:  Join(a),
+			r:  &rest[0],
+			fs: rest[1:],
+		},
+	}
+}
+
+func Flatten(poly PolyExp) []PolyExp {
+	if !poly.IsSum() {
+		// Flatten does not recurse over products
+		// Its intended use is over polynomials that have already distributed all products
 		return []PolyExp{poly}
 	}
 
 	// Sum case
 	flattened := make([]PolyExp, 0)
-	for _, p := range poly.s.ps {
+	for _, p := range poly.sum().ps {
 		flattened = append(flattened, Flatten(p)...)
 	}
 	return flattened
@@ -159,8 +608,8 @@ func Flatten(poly PolyExp) []PolyExp {
 
 func Zero() PolyExp {
 	return PolyExp{
-		c: &ConstantExp{
-			c: 0,
+		e: &ConstantExp{
+			c: Integer(0),
 		},
 	}
 }
@@ -170,30 +619,39 @@ func Join(polys []PolyExp) *PolyExp {
 		return &polys[0]
 	}
 	return &PolyExp{
-		s: &SumExp{
+		e: &SumExp{
 			ps: polys,
 		},
 	}
 }
 
 func ApplyProducts(mult int, poly PolyExp) (*PolyExp, error) {
-	if mult == 0 {
+	return applyProducts(Integer(mult), poly)
+}
+
+func applyProducts(mult Rational, poly PolyExp) (*PolyExp, error) {
+	if mult.IsZero() {
 		return &PolyExp{
-			c: &ConstantExp{
-				c: 0,
+			e: &ConstantExp{
+				c: Integer(0),
 			},
 		}, nil
 	}
 	if poly.IsConstant() {
-		poly.c.c *= mult
-		return &poly, nil
+		return &PolyExp{
+			e: &ConstantExp{
+				c: poly.constant().c.Mul(mult),
+			},
+		}, nil
 	}
 
-	if poly.IsMon() {
+	if poly.IsMon() || poly.IsParam() || poly.isCompound() {
 		return &PolyExp{
-			p: &ProductExp{
-				l: &ConstantExp{
-					c: mult,
+			e: &ProductExp{
+				l: &PolyExp{
+					e: &ConstantExp{
+						c: mult,
+					},
 				},
 				r: &poly,
 			},
@@ -201,21 +659,91 @@ func ApplyProducts(mult int, poly PolyExp) (*PolyExp, error) {
 	}
 
 	if poly.IsSum() {
-		sum := poly.s
+		sum := poly.sum()
 		ret := PolyExp{
-			s: &SumExp{
+			e: &SumExp{
 				ps: make([]PolyExp, len(sum.ps)),
 			},
 		}
 		for i, p := range sum.ps {
-			appliedPoly, err := ApplyProducts(mult, p)
+			appliedPoly, err := applyProducts(mult, p)
 			if err != nil {
 				return nil, err
 			}
-			ret.s.ps[i] = *appliedPoly
+			ret.sum().ps[i] = *appliedPoly
 		}
 		return &ret, nil
 	}
 	// Product case
-	return ApplyProducts(poly.p.l.c*mult, *poly.p.r)
+	if len(poly.product().fs) > 0 {
+		// Only the coefficient of a product of several factors is applied
+		l := PolyExp{e: &ConstantExp{c: mult}}
+		if poly.product().l.IsConstant() {
+			l = PolyExp{e: &ConstantExp{c: poly.product().l.constant().c.Mul(mult)}}
+		} else if !mult.Equal(Integer(1)) {
+			l = PolyExp{e: &ProductExp{l: &l, r: poly.product().l}}
+		} else {
+			l = *poly.product().l
+		}
+		return &PolyExp{
+			e: &ProductExp{
+				l:  &l,
+				r:  poly.product().r,
+				fs: poly.product().fs,
+			},
+		}, nil
+	}
+	if poly.product().l.IsConstant() {
+		return applyProducts(poly.product().l.constant().c.Mul(mult), *poly.product().r)
+	}
+	// Symbolic coefficients are carried through to the leaves
+	applied, err := applyProducts(mult, *poly.product().r)
+	if err != nil {
+		return nil, err
+	}
+	return distributeCoefficient(*poly.product().l, *applied), nil
+}
+
+// Multiply a symbolic coefficient through to every term of a polynomial
+// with products already applied
+func distributeCoefficient(coeff PolyExp, poly PolyExp) *PolyExp {
+	if poly.IsSum() {
+		ret := PolyExp{
+			e: &SumExp{
+				ps: make([]PolyExp, len(poly.sum().ps)),
+			},
+		}
+		for i, p := range poly.sum().ps {
+			ret.sum().ps[i] = *distributeCoefficient(coeff, p)
+		}
+		return &ret
+	}
+	if poly.IsProduct() {
+		return &PolyExp{
+			e: &ProductExp{
+				l: &PolyExp{
+					e: &ProductExp{
+						l: poly.product().l,
+						r: &coeff,
+					},
+				},
+				r:  poly.product().r,
+				fs: poly.product().fs,
+			},
+		}
+	}
+	if poly.IsConstant() {
+		return &PolyExp{
+			e: &ProductExp{
+				l: &poly,
+				r: &coeff,
+			},
+		}
+	}
+	return &PolyExp{
+		e: &ProductExp{
+			l: &coeff,
+			r: &poly,
+		},
+	}
 }
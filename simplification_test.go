@@ -1,9 +1,12 @@
 package symdiff_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	. "github.com/zenground0/symdiff"
 )
 
@@ -46,6 +49,21 @@ func TestApplyProducts(t *testing.T) {
 	assert.Equal(t, "( + ( * 6 ( ^ x 0 ) ) ( * 15 ( ^ x 0 ) ) )", polyProd.ToSExp().String())
 }
 
+// The distribute and flatten rule sets run until done however wide the sums
+func TestSimplifyWideSums(t *testing.T) {
+	n := DefaultMaxPasses + 20
+	terms := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		terms = append(terms, fmt.Sprintf("( * %d ( + ( ^ x %d ) 1 ) )", i, i))
+	}
+	s, err := Simplify(polyFromString(t, "( * 2 ( + "+strings.Join(terms, " ")+" ) )"))
+	require.NoError(t, err)
+	folded := Flatten(*s)
+	assert.Len(t, folded, n+1)
+	assert.Equal(t, "( * 2 ( ^ x 1 ) )", folded[0].ToSExp().String())
+	assert.Equal(t, fmt.Sprint(n*(n+1)), folded[n].ToSExp().String())
+}
+
 func TestFold(t *testing.T) {
 	poly := polyFromString(t, "( + ( ^ x 2) ( * 2 ( ^ x 2 ) ) )")
 	polyFold := Join(Fold(Flatten(poly)))
@@ -65,3 +83,111 @@ func TestFold(t *testing.T) {
 	polyFold = Join(Fold(Flatten(poly)))
 	assert.Equal(t, "10", polyFold.ToSExp().String())
 }
+
+func TestFoldSymbolicExponents(t *testing.T) {
+	// provably equal exponents are combined
+	poly := polyFromString(t, "( + ( ^ x ( + n 1 ) ) ( * 2 ( ^ x ( + 1 n ) ) ) )", "n")
+	polyFold := Join(Fold(Flatten(poly)))
+	assert.Equal(t, "( + ( * 3 ( ^ x ( + n 1 ) ) ) 0 )", polyFold.ToSExp().String())
+
+	// distinct exponents are kept apart, integer powers first
+	poly = polyFromString(t, "( + ( ^ x n ) ( ^ x m ) ( ^ x 2 ) ( ^ x n ) )", "m", "n")
+	polyFold = Join(Fold(Flatten(poly)))
+	assert.Equal(t, "( + ( ^ x 2 ) ( ^ x m ) ( * 2 ( ^ x n ) ) 0 )", polyFold.ToSExp().String())
+
+	// exponents that simplify to integers fold with integer powers
+	poly = polyFromString(t, "( + ( ^ x ( + n 2 ( * -1 n ) ) ) ( ^ x 2 ) ( ^ x ( + 3 -3 ) ) )", "n")
+	s, err := Simplify(poly)
+	assert.NoError(t, err)
+	assert.Equal(t, "( + ( * 2 ( ^ x 2 ) ) 1 )", s.ToSExp().String())
+}
+
+func TestFoldSymbolicCoefficients(t *testing.T) {
+	// a x^2 + 2b x^2 + b x + 3 x + c + 4
+	poly := polyFromString(t, "( + ( * a ( ^ x 2 ) ) ( * 2 ( * b ( ^ x 2 ) ) ) ( * b ( ^ x 1 ) ) ( * 3 ( ^ x 1 ) ) c 4 )", "a", "b", "c")
+	s, err := Simplify(poly)
+	assert.NoError(t, err)
+	assert.Equal(t, "( + ( * ( + b 3 ) ( ^ x 1 ) ) ( * ( + a ( * 2 b ) ) ( ^ x 2 ) ) c 4 )", s.ToSExp().String())
+
+	// coefficients are distributed and products of parameters collected
+	poly = polyFromString(t, "( + ( * ( + a b ) ( ^ x 2 ) ) ( * -1 ( * b ( ^ x 2 ) ) ) ( * ( * a b ) ( ^ x 1 ) ) ( * b ( * 2 ( * a ( ^ x 1 ) ) ) ) )", "a", "b", "c")
+	s, err = Simplify(poly)
+	assert.NoError(t, err)
+	assert.Equal(t, "( + ( * ( * 3 ( * a b ) ) ( ^ x 1 ) ) ( * a ( ^ x 2 ) ) )", s.ToSExp().String())
+
+	// powers of parameters collect with their products
+	poly = polyFromString(t, "( + ( * ( ^ a 2 ) ( ^ x 1 ) ) ( * ( * a a ) ( ^ x 1 ) ) ( * ( * a ( ^ a -1 ) ) ( ^ x 1 ) ) )", "a", "b", "c")
+	s, err = Simplify(poly)
+	assert.NoError(t, err)
+	assert.Equal(t, "( * ( + ( * 2 ( ^ a 2 ) ) 1 ) ( ^ x 1 ) )", s.ToSExp().String())
+
+	// cancelled coefficients drop the term
+	poly = polyFromString(t, "( + ( * a ( ^ x 2 ) ) ( * -1 ( * a ( ^ x 2 ) ) ) a )", "a", "b", "c")
+	s, err = Simplify(poly)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", s.ToSExp().String())
+}
+
+func TestFoldRationalExponents(t *testing.T) {
+	poly := polyFromString(t, "( + ( ^ x 1/2 ) ( * 2 ( ^ x 2/4 ) ) ( * 1/2 ( ^ x -1 ) ) ( ^ x 1 ) )")
+	s, err := Simplify(poly)
+	assert.NoError(t, err)
+	assert.Equal(t, "( + ( * 1/2 ( ^ x -1 ) ) ( * 3 ( ^ x 1/2 ) ) ( ^ x 1 ) )", s.ToSExp().String())
+
+	// powers of expressions fold like monomials, square roots of squares are kept
+	poly = polyFromString(t, "( + ( sqrt ( + 1 ( ^ x 2 ) ) ) ( sqrt ( + ( ^ x 2 ) 1 ) ) ( sqrt ( ^ x 2 ) ) ( ^ ( ^ x 2 ) 2 ) ( ^ 4 -2 ) )")
+	s, err = Simplify(poly)
+	assert.NoError(t, err)
+	assert.Equal(t, "( + ( ^ x 4 ) ( * 2 ( ^ ( + ( ^ x 2 ) 1 ) 1/2 ) ) ( ^ ( ^ x 2 ) 1/2 ) 1/16 )", s.ToSExp().String())
+}
+
+// Inputs are reused across calls and must never be modified
+func TestTransformationsDoNotMutate(t *testing.T) {
+	for _, raw := range []string{
+		"( * 3 ( + ( ^ x 2 ) ( * 2 ( + ( ^ x 1 ) 4 ) ) ) )",
+		"( + ( * a ( ^ x n ) ) ( * ( + a 2 ) ( + ( ^ x 2 ) b ) ) )",
+		"( * 2 ( ^ x 1 ) ( sqrt ( + ( ^ x 2 ) 1 ) ) )",
+		"( + ( abs ( * 3 ( ^ x 1 ) ) ) " + hinge + " )",
+	} {
+		poly := polyFromString(t, raw, "a", "b", "n")
+		snapshot := poly.Clone()
+		printed := poly.ToSExp().String()
+
+		_, err := ApplyProducts(5, poly)
+		assert.NoError(t, err)
+		Fold(Flatten(poly))
+		_, err = Simplify(poly)
+		assert.NoError(t, err)
+		derivative, err := Differentiate("x", poly)
+		assert.NoError(t, err)
+		_, err = Evaluate(poly, map[Symbol]float64{"x": 2, "n": 3, "a": 1, "b": 5})
+		assert.NoError(t, err)
+		assert.Equal(t, snapshot, poly, raw)
+		assert.Equal(t, printed, poly.ToSExp().String())
+
+		// derivatives share structure with their input but simplifying them
+		// leaves both alone
+		derivativeSnapshot := derivative.Clone()
+		first, err := Simplify(*derivative)
+		assert.NoError(t, err)
+		second, err := Simplify(*derivative)
+		assert.NoError(t, err)
+		assert.Equal(t, first.ToSExp().String(), second.ToSExp().String())
+		assert.Equal(t, derivativeSnapshot, *derivative, raw)
+		assert.Equal(t, snapshot, poly, raw)
+	}
+}
+
+func TestReuseAfterDifferentiate(t *testing.T) {
+	poly := polyFromString(t, "( * 3 ( + ( * 2 ( ^ x 2 ) ) 1 ) )")
+	derivative, err := Differentiate("x", poly)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		s, err := Simplify(*derivative)
+		assert.NoError(t, err)
+		assert.Equal(t, "( * 12 ( ^ x 1 ) )", s.ToSExp().String())
+		s, err = Simplify(poly)
+		assert.NoError(t, err)
+		assert.Equal(t, "( + ( * 6 ( ^ x 2 ) ) 3 )", s.ToSExp().String())
+	}
+}
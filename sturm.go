@@ -0,0 +1,213 @@
+package symdiff
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+/*
+   Real root counting and isolation
+
+   The Sturm sequence of a square free p is p, p', and then the negated remainders of
+   dividing each polynomial by the next until the remainder vanishes.  The
+   number of distinct real roots in (a, b] is the number of sign changes of
+   the sequence at a minus the number of sign changes at b.  Repeated roots
+   are removed first dividing p by gcd(p, p').
+
+   Roots are isolated by bisecting (-B, B], B a power of 2 bounding every root
+   (Cauchy), until each interval holds a single root.  Interval endpoints are
+   dyadic so they are exact floating point numbers and signs are evaluated
+   exactly.
+*/
+
+// Interval (Lo, Hi] containing a single distinct real root
+type RootInterval struct {
+	Lo, Hi float64
+}
+
+// Number of distinct real roots of a polynomial in one variable in (lo, hi]
+func CountRealRoots(poly PolyExp, lo, hi float64) (int, error) {
+	p, err := rootPolynomial(poly)
+	if err != nil {
+		return 0, err
+	}
+	if lo >= hi {
+		return 0, nil
+	}
+	s, err := sturmSequence(p)
+	if err != nil {
+		return 0, err
+	}
+	return s.signChanges(lo) - s.signChanges(hi), nil
+}
+
+// Disjoint intervals containing one distinct real root each, in increasing
+// order
+func IsolateRealRoots(poly PolyExp) ([]RootInterval, error) {
+	p, err := rootPolynomial(poly)
+	if err != nil {
+		return nil, err
+	}
+	if p.degree() < 1 {
+		return nil, nil
+	}
+	s, err := sturmSequence(p)
+	if err != nil {
+		return nil, err
+	}
+	bound, err := rootBound(p)
+	if err != nil {
+		return nil, err
+	}
+	return s.isolate(bound)
+}
+
+// Polynomial whose roots are asked for, not the zero polynomial
+func rootPolynomial(poly PolyExp) (upoly, error) {
+	if err := poly.check(); err != nil {
+		return nil, err
+	}
+	_, p, err := toUnivariate(poly)
+	if err != nil {
+		return nil, fmt.Errorf("%s, can only find roots of polynomials in one variable with rational coefficients", err)
+	}
+	if p.degree() < 0 {
+		return nil, fmt.Errorf("every number is a root of the zero polynomial")
+	}
+	return p, nil
+}
+
+type sturm []upoly
+
+// Sturm sequence of the square free part of p, which has the same distinct
+// roots
+func sturmSequence(p upoly) (sturm, error) {
+	g, err := p.gcd(p.derivative())
+	if err != nil {
+		return nil, err
+	}
+	if p, _, err = p.divmod(g); err != nil {
+		return nil, err
+	}
+	ret := sturm{positivePart(p)}
+	next := positivePart(p.derivative())
+	for next.degree() >= 0 {
+		ret = append(ret, next)
+		_, r, err := ret[len(ret)-2].divmod(next)
+		if err != nil {
+			return nil, err
+		}
+		next = positivePart(r.scale(Integer(-1)))
+	}
+	return ret, nil
+}
+
+// p scaled by a positive rational to coprime integer coefficients, scaling
+// by a positive number keeps the signs of the Sturm sequence
+func positivePart(p upoly) upoly {
+	content, prim := p.primitive()
+	if content.Sign() < 0 {
+		return prim.scale(Integer(-1))
+	}
+	return prim
+}
+
+func (s sturm) signChanges(x float64) int {
+	ret := 0
+	last := 0
+	for _, p := range s {
+		sign := signAt(p, x)
+		if sign == 0 {
+			continue
+		}
+		if last != 0 && sign != last {
+			ret++
+		}
+		last = sign
+	}
+	return ret
+}
+
+// Intervals of single roots in (-bound, bound]
+func (s sturm) isolate(bound float64) ([]RootInterval, error) {
+	if len(s) == 0 || s[0].degree() < 0 {
+		return nil, fmt.Errorf("every number is a root of the zero polynomial")
+	}
+	var ret []RootInterval
+	var bisect func(lo, hi float64, vlo, vhi int)
+	bisect = func(lo, hi float64, vlo, vhi int) {
+		n := vlo - vhi
+		if n == 0 {
+			return
+		}
+		mid := lo + (hi-lo)/2
+		if n == 1 || mid <= lo || mid >= hi {
+			// roots closer than floating point resolution are reported
+			// together
+			ret = append(ret, RootInterval{Lo: lo, Hi: hi})
+			return
+		}
+		vmid := s.signChanges(mid)
+		bisect(lo, mid, vlo, vmid)
+		bisect(mid, hi, vmid, vhi)
+	}
+	bisect(-bound, bound, s.signChanges(-bound), s.signChanges(bound))
+	return ret, nil
+}
+
+// Refine an interval of a single root to floating point resolution
+func (s sturm) refine(r RootInterval) float64 {
+	lo, hi := r.Lo, r.Hi
+	vhi := s.signChanges(hi)
+	for {
+		mid := lo + (hi-lo)/2
+		if mid <= lo || mid >= hi || hi-lo <= 0x1p-53*math.Abs(hi) {
+			break
+		}
+		vmid := s.signChanges(mid)
+		if vmid == vhi {
+			hi, vhi = mid, vmid
+		} else {
+			lo = mid
+		}
+	}
+	return hi
+}
+
+// Exact sign of p(x)
+func signAt(p upoly, x float64) int {
+	bx := new(big.Rat).SetFloat64(x)
+	ret := new(big.Rat)
+	for i := len(p) - 1; i >= 0; i-- {
+		ret.Mul(ret, bx)
+		ret.Add(ret, p[i].rat())
+	}
+	return ret.Sign()
+}
+
+// Power of 2 above the absolute value of every complex root,
+// 1 + max |p[i] / lc|, computed exactly
+func rootBound(p upoly) (float64, error) {
+	if p.degree() < 1 {
+		return 0, fmt.Errorf("no root bound of a constant polynomial")
+	}
+	lc := new(big.Rat).Abs(p.lc().rat())
+	m := new(big.Rat)
+	for _, c := range p[:p.degree()] {
+		r := new(big.Rat).Abs(c.rat())
+		if r.Quo(r, lc).Cmp(m) > 0 {
+			m = r
+		}
+	}
+	m.Add(m, big.NewRat(1, 1))
+	ret := big.NewRat(1, 1)
+	for ret.Cmp(m) <= 0 {
+		ret.Mul(ret, big.NewRat(2, 1))
+	}
+	bound, _ := ret.Float64()
+	if math.IsInf(bound, 0) {
+		return 0, fmt.Errorf("root bound beyond floating point range")
+	}
+	return bound, nil
+}
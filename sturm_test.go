@@ -0,0 +1,54 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestRealRoots(t *testing.T) {
+	// ( x - 1 )^2 ( x + 2 ) ( x^2 - 2 ) ( x^2 + 1 )
+	poly := polyFromString(t, "( * 1 ( ^ ( + ( ^ x 1 ) -1 ) 2 ) ( + ( ^ x 1 ) 2 ) ( + ( ^ x 2 ) -2 ) ( + ( ^ x 2 ) 1 ) )")
+	n, err := CountRealRoots(poly, -10, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	// roots at the upper end are counted, at the lower end they are not
+	n, err = CountRealRoots(poly, -2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = CountRealRoots(poly, 1.5, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	is, err := IsolateRealRoots(poly)
+	require.NoError(t, err)
+	require.Len(t, is, 4)
+	roots := []float64{-2, -1.4142135623730951, 1, 1.4142135623730951}
+	for i, r := range roots {
+		assert.Less(t, is[i].Lo, r)
+		assert.LessOrEqual(t, r, is[i].Hi)
+		if i > 0 {
+			assert.LessOrEqual(t, is[i-1].Hi, is[i].Lo)
+		}
+	}
+
+	is, err = IsolateRealRoots(polyFromString(t, "( + ( ^ x 2 ) 1 )"))
+	require.NoError(t, err)
+	assert.Empty(t, is)
+	is, err = IsolateRealRoots(polyFromString(t, "7"))
+	require.NoError(t, err)
+	assert.Empty(t, is)
+	_, err = CountRealRoots(polyFromString(t, "0"), -1, 1)
+	assert.Error(t, err)
+	_, err = IsolateRealRoots(polyFromString(t, "0"))
+	assert.Error(t, err)
+
+	// coefficients overflowing machine integers in the Sturm sequence
+	is, err = IsolateRealRoots(polyFromString(t, "( * 1000000 ( + ( ^ x 20 ) -1 ) )"))
+	require.NoError(t, err)
+	require.Len(t, is, 2)
+	assert.True(t, is[0].Lo < -1 && -1 <= is[0].Hi)
+	assert.True(t, is[1].Lo < 1 && 1 <= is[1].Hi)
+}
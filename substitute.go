@@ -0,0 +1,114 @@
+package symdiff
+
+import (
+	"fmt"
+)
+
+/*
+   Substitution
+
+   Symbols are replaced by expressions everywhere they occur, simultaneously
+   so replacements are not substituted into again and x -> y, y -> x swaps
+   the two.  A monomial ( ^ x n ) becomes ( ^ g n ) for the replacement g of
+   x, a monomial again when g is a monomial or a parameter, and parameters
+   are replaced as they are.
+
+     ( + ( ^ x 2 ) ( * a ( ^ x 1 ) ) ), x -> ( + ( ^ t 1 ) 1 )
+       ==> ( + ( ^ ( + ( ^ t 1 ) 1 ) 2 ) ( * a ( + ( ^ t 1 ) 1 ) ) )
+
+   Coefficients of products that stop being coefficients become factors
+   ( * 1 ... ).  The result is not simplified.
+*/
+
+// Replace symbols with expressions
+func Substitute(poly PolyExp, with map[Symbol]PolyExp) (*PolyExp, error) {
+	if err := poly.check(); err != nil {
+		return nil, err
+	}
+	for x, g := range with {
+		if err := g.check(); err != nil {
+			return nil, fmt.Errorf("%s, invalid replacement for %s", err, x)
+		}
+	}
+	ret, err := substituteExp(poly, with)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// f(g), the variable of f replaced by g
+func Compose(f, g PolyExp) (*PolyExp, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	vars := f.Variables()
+	if len(vars) > 1 {
+		return nil, fmt.Errorf("can only compose functions of one variable, %s is in %d variables", f.ToSExp().String(), len(vars))
+	}
+	if len(vars) == 0 {
+		return &f, nil
+	}
+	return Substitute(f, map[Symbol]PolyExp{vars[0]: g})
+}
+
+func substituteExp(exp PolyExp, with map[Symbol]PolyExp) (PolyExp, error) {
+	switch {
+	case exp.IsParam():
+		if g, ok := with[exp.param().a]; ok {
+			return g, nil
+		}
+		return exp, nil
+	case exp.IsMon():
+		return substituteMon(*exp.mon(), with)
+	}
+	children := exp.e.children()
+	for i := range children {
+		c, err := substituteExp(children[i], with)
+		if err != nil {
+			return PolyExp{}, err
+		}
+		children[i] = c
+	}
+	if exp.IsProduct() && !children[0].isCoefficient() {
+		one := Const(Integer(1))
+		return PolyExp{e: &ProductExp{l: &one, r: &children[0], fs: children[1:]}}, nil
+	}
+	e, err := exp.e.rebuild(children)
+	if err != nil {
+		return PolyExp{}, fmt.Errorf("%s, failed to substitute into %s", err, exp.ToSExp().String())
+	}
+	return PolyExp{e: e}, nil
+}
+
+func substituteMon(m MonomialExp, with map[Symbol]PolyExp) (PolyExp, error) {
+	g, ok := with[m.x]
+	if m.e != nil {
+		e, err := substituteExp(*m.e, with)
+		if err != nil {
+			return PolyExp{}, err
+		}
+		x := m.x
+		if ok {
+			// only a variable or parameter can be raised to a symbolic power
+			switch {
+			case g.IsMon() && g.mon().e == nil && g.mon().n.Equal(Integer(1)):
+				x = g.mon().x
+			case g.IsParam():
+				x = g.param().a
+			default:
+				return PolyExp{}, fmt.Errorf("can not substitute %s for %s in a symbolic power", g.ToSExp().String(), m.x)
+			}
+		}
+		return SymbolicPow(x, e)
+	}
+	switch {
+	case !ok:
+		return PolyExp{e: &m}, nil
+	case m.n.Equal(Integer(1)):
+		return g, nil
+	case g.IsMon() && g.mon().e == nil:
+		return Pow(g.mon().x, g.mon().n.Mul(m.n))
+	}
+	return Raise(g, m.n)
+}
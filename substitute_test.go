@@ -0,0 +1,68 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func TestSubstitute(t *testing.T) {
+	cases := []struct {
+		raw      string
+		with     map[Symbol]string
+		expected string
+	}{
+		{"( + ( ^ x 2 ) ( * a ( ^ x 1 ) ) )", map[Symbol]string{"x": "( + ( ^ t 1 ) 1 )"}, "( + ( ^ ( + ( ^ t 1 ) 1 ) 2 ) ( * a ( + ( ^ t 1 ) 1 ) ) )"},
+		{"( + ( ^ x 2 ) ( ^ y 1 ) )", map[Symbol]string{"x": "( ^ y 1 )", "y": "( ^ x 1 )"}, "( + ( ^ y 2 ) ( ^ x 1 ) )"},
+		{"( * a ( ^ x 3 ) )", map[Symbol]string{"a": "( ^ t 1 )", "x": "( ^ t 2 )"}, "( * 1 ( ^ t 1 ) ( ^ t 6 ) )"},
+		// symbolic powers of a variable renamed
+		{"( ^ x n )", map[Symbol]string{"n": "( + b 1 )", "x": "( ^ y 1 )"}, "( ^ y ( + b 1 ) )"},
+		{"( abs ( + ( ^ x 1 ) -1 ) )", map[Symbol]string{"x": "3"}, "( abs ( + 3 -1 ) )"},
+		{"( ^ y 2 )", map[Symbol]string{"x": "3"}, "( ^ y 2 )"},
+	}
+	for _, c := range cases {
+		with := make(map[Symbol]PolyExp)
+		for x, raw := range c.with {
+			with[x] = polyFromString(t, raw, "b")
+		}
+		out, err := Substitute(polyFromString(t, c.raw, "a", "n"), with)
+		require.NoError(t, err, c.raw)
+		assert.Equal(t, c.expected, out.ToSExp().String(), c.raw)
+	}
+
+	_, err := Substitute(polyFromString(t, "( ^ x n )", "n"), map[Symbol]PolyExp{"x": polyFromString(t, "( + ( ^ y 1 ) 1 )")})
+	assert.Error(t, err)
+	_, err = Substitute(polyFromString(t, "( ^ x n )", "n"), map[Symbol]PolyExp{"n": polyFromString(t, "( ^ x 1 )")})
+	assert.Error(t, err)
+}
+
+func TestCompose(t *testing.T) {
+	f := polyFromString(t, "( + ( ^ x 3 ) ( * 2 ( ^ x 1 ) ) )")
+	g := polyFromString(t, "( + ( ^ t 2 ) 1 )")
+	fg, err := Compose(f, g)
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( ^ ( + ( ^ t 2 ) 1 ) 3 ) ( * 2 ( + ( ^ t 2 ) 1 ) ) )", fg.ToSExp().String())
+
+	// chain rule, d/dt f(g(t)) = f'(g(t)) g'(t)
+	lhs, err := Differentiate("t", *fg)
+	require.NoError(t, err)
+	df, err := Differentiate("x", f)
+	require.NoError(t, err)
+	dfg, err := Compose(*df, g)
+	require.NoError(t, err)
+	dg, err := Differentiate("t", g)
+	require.NoError(t, err)
+	rhs, err := Mul(*dfg, *dg)
+	require.NoError(t, err)
+	eq, err := Equivalent(*lhs, rhs)
+	require.NoError(t, err)
+	assert.True(t, eq.Equivalent && eq.Exact)
+
+	_, err = Compose(polyFromString(t, "( * 1 ( ^ x 1 ) ( ^ y 1 ) )"), g)
+	assert.Error(t, err)
+	c, err := Compose(polyFromString(t, "( + a 1 )", "a"), g)
+	require.NoError(t, err)
+	assert.Equal(t, "( + a 1 )", c.ToSExp().String())
+}
@@ -4,7 +4,10 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/urfave/cli/v2"
 	. "github.com/zenground0/symdiff"
@@ -16,6 +19,13 @@ func main() {
 		replCmd,
 		ddxCmd,
 		simplifyCmd,
+		evalCmd,
+		equivCmd,
+		factorCmd,
+		collectCmd,
+		hornerCmd,
+		substCmd,
+		rootsCmd,
 	}
 	app := &cli.App{
 		Name:     "symdiff",
@@ -35,8 +45,16 @@ func main() {
 var replCmd = &cli.Command{
 	Name:  "repl",
 	Usage: "d/dx, simplify, print loop",
+	Flags: []cli.Flag{
+		paramFlag,
+	},
 	Action: func(cctx *cli.Context) error {
 		fmt.Printf("\nd/dx, simplify, print\n")
+		fmt.Printf("enter an expression or %shelp for commands\n", ReplCommandPrefix)
+		session := NewSession()
+		if err := session.Declare(params(cctx)...); err != nil {
+			return err
+		}
 		bio := bufio.NewReader(os.Stdin)
 		// rough repl
 		for {
@@ -44,66 +62,295 @@ var replCmd = &cli.Command{
 			fmt.Printf("\nd/dx ")
 			// await user input
 			input, err := bio.ReadString('\n')
+			if err == io.EOF {
+				return nil
+			}
 			if err != nil {
 				fmt.Printf("Error reading user input %s\n", err)
 				continue
 			}
-			// parse
-			var sexp SExp
-			if err := sexp.Parse(input); err != nil {
-				fmt.Printf("Error parsing user input as sexp: %s\n", err)
+			input = strings.TrimSpace(input)
+			if strings.HasPrefix(input, ReplCommandPrefix) {
+				out, err := replCommand(session, strings.TrimPrefix(input, ReplCommandPrefix))
+				if err != nil {
+					fmt.Printf("Error: %s\n", err)
+					continue
+				}
+				fmt.Printf("%s", out)
 				continue
 			}
-			var poly PolyExp
-			if err := poly.Parse(sexp); err != nil {
-				fmt.Printf("Error parsing user input as polynomial: %s\n", err)
+			// parse
+			poly, err := session.Parse(input)
+			if err != nil {
+				fmt.Printf("Error parsing user input: %s\n", err)
 				continue
 			}
 
 			// differentiate in x
-			d, err := Differentiate(Symbol("x"), poly)
+			d, err := session.Differentiate(Symbol("x"), poly)
 			if err != nil {
 				fmt.Printf("Error taking derivative: %s\n", err)
 				continue
 			}
 			// simplify
-			s, err := Simplify(*d)
+			s, err := session.Simplify(*d)
 			if err != nil {
 				fmt.Printf("Error simplifying expression %s: %s", d.ToSExp().String(), err)
 				continue
 			}
 
 			// return value
-			prettyString, err := RainbowParens(s.ToSExp().String(), Rainbow)
-			if err != nil {
-				fmt.Printf("Error formatting output: %s\n", err)
-			}
-			fmt.Printf("%s\n", prettyString)
+			fmt.Printf("%s\n", pretty(s))
 		}
 	},
 }
 
+// Prefix of REPL commands, i.e. `passes
+const ReplCommandPrefix = "`"
+
+const replHelp = `commands
+  <poly expr>                    differentiate in x and simplify
+  ` + "`" + `d/dx <poly expr>               differentiate in x and simplify
+  ` + "`" + `s <poly expr>                  simplify with enabled passes and rules
+  ` + "`" + `collect <symbol> <poly expr>   group terms by powers of a variable
+  ` + "`" + `param [<symbol> ...]           declare parameters, other symbols are variables, list them without symbols
+  ` + "`" + `explain <poly expr>            differentiate in x and simplify showing each step
+  ` + "`" + `passes                         list simplification passes
+  ` + "`" + `enable <pass>                  enable a pass
+  ` + "`" + `disable <pass>                 disable a pass
+  ` + "`" + `run <pass> <poly expr>         run a single pass
+  ` + "`" + `rule <pattern> => <template>   add a rewrite rule applied after the passes
+  ` + "`" + `rules                          list rewrite rules
+  ` + "`" + `unrule <n>                     remove the n-th rewrite rule
+  ` + "`" + `strategy bottomup|topdown|root  where rewrite rules are applied
+  ` + "`" + `order <order>                  order of terms: ascending, descending, lex, grlex or grevlex
+  ` + "`" + `save <file>                    save rewrite rules to a file
+  ` + "`" + `load <file>                    add rewrite rules from a file
+  ` + "`" + `cache                          show cache hits and misses
+`
+
+// Run a REPL command against the session returning its output
+func replCommand(session *Session, input string) (string, error) {
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(input), " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case "help":
+		return replHelp, nil
+	case "d/dx":
+		poly, err := parseSessionPoly(session, arg)
+		if err != nil {
+			return "", err
+		}
+		d, err := session.Differentiate(Symbol("x"), poly)
+		if err != nil {
+			return "", fmt.Errorf("error taking derivative: %s", err)
+		}
+		s, err := session.Simplify(*d)
+		if err != nil {
+			return "", fmt.Errorf("error simplifying expression %s: %s", d.ToSExp().String(), err)
+		}
+		return pretty(s) + "\n", nil
+	case "s":
+		poly, err := parseSessionPoly(session, arg)
+		if err != nil {
+			return "", err
+		}
+		s, err := session.Simplify(poly)
+		if err != nil {
+			return "", fmt.Errorf("error simplifying expression %s: %s", poly.ToSExp().String(), err)
+		}
+		return pretty(s) + "\n", nil
+	case "collect":
+		sym, raw, _ := strings.Cut(arg, " ")
+		if !IsSymbol(sym) {
+			return "", fmt.Errorf("invalid symbol %s", sym)
+		}
+		poly, err := parseSessionPoly(session, raw)
+		if err != nil {
+			return "", err
+		}
+		c, err := Collect(poly, Symbol(sym))
+		if err != nil {
+			return "", fmt.Errorf("error collecting expression: %s", err)
+		}
+		return pretty(*c) + "\n", nil
+	case "explain":
+		poly, err := parseSessionPoly(session, arg)
+		if err != nil {
+			return "", err
+		}
+		var tr Trace
+		d, err := DifferentiateTrace(Symbol("x"), poly, &tr)
+		if err != nil {
+			return "", fmt.Errorf("error taking derivative: %s", err)
+		}
+		s, err := session.SimplifyTrace(*d, &tr)
+		if err != nil {
+			return "", fmt.Errorf("error simplifying expression %s: %s", d.ToSExp().String(), err)
+		}
+		return tr.String() + pretty(s) + "\n", nil
+	case "param":
+		if arg == "" {
+			var out strings.Builder
+			for _, a := range session.Params() {
+				fmt.Fprintf(&out, "%s\n", a)
+			}
+			return out.String(), nil
+		}
+		var declared []Symbol
+		for _, a := range strings.Fields(arg) {
+			declared = append(declared, Symbol(a))
+		}
+		return "", session.Declare(declared...)
+	case "passes":
+		var out strings.Builder
+		for _, p := range session.Passes() {
+			status := "enabled"
+			if !p.Enabled {
+				status = "disabled"
+			}
+			fmt.Fprintf(&out, "%-14s %s\n", p.Name, status)
+		}
+		fmt.Fprintf(&out, "terms in %s order\n", session.Order())
+		fmt.Fprintf(&out, "%d rewrite rules applied %s\n", len(session.Rules()), session.Strategy())
+		return out.String(), nil
+	case "enable":
+		return "", session.Enable(arg)
+	case "disable":
+		return "", session.Disable(arg)
+	case "run":
+		name, raw, _ := strings.Cut(arg, " ")
+		poly, err := parseSessionPoly(session, raw)
+		if err != nil {
+			return "", err
+		}
+		out, err := session.RunPass(name, poly)
+		if err != nil {
+			return "", err
+		}
+		return pretty(out) + "\n", nil
+	case "rule":
+		_, err := session.AddRule(arg)
+		return "", err
+	case "rules":
+		var out strings.Builder
+		for i, r := range session.Rules() {
+			fmt.Fprintf(&out, "%d: %s\n", i, r.String())
+		}
+		return out.String(), nil
+	case "unrule":
+		i, err := strconv.Atoi(arg)
+		if err != nil {
+			return "", fmt.Errorf("invalid rule number %s", arg)
+		}
+		return "", session.RemoveRule(i)
+	case "strategy":
+		st, err := ParseStrategy(arg)
+		if err != nil {
+			return "", err
+		}
+		session.SetStrategy(st)
+		return "", nil
+	case "order":
+		order, err := ParseTermOrder(arg)
+		if err != nil {
+			return "", err
+		}
+		session.SetOrder(order)
+		return "", nil
+	case "save":
+		f, err := os.Create(arg)
+		if err != nil {
+			return "", fmt.Errorf("error creating rules file: %s", err)
+		}
+		defer f.Close()
+		return "", session.WriteRules(f)
+	case "load":
+		f, err := os.Open(arg)
+		if err != nil {
+			return "", fmt.Errorf("error opening rules file: %s", err)
+		}
+		defer f.Close()
+		return "", session.LoadRules(f)
+	case "cache":
+		st := session.CacheStats()
+		return fmt.Sprintf("%d hits, %d misses, %d evictions, %d results kept\n", st.Hits, st.Misses, st.Evictions, st.Size), nil
+	}
+	return "", fmt.Errorf("unknown command %s%s, see %shelp", ReplCommandPrefix, cmd, ReplCommandPrefix)
+}
+
+func parsePoly(raw string, params []Symbol) (PolyExp, error) {
+	var sexp SExp
+	if err := sexp.Parse(raw); err != nil {
+		return PolyExp{}, fmt.Errorf("error parsing user input as sexp: %s", err)
+	}
+	var poly PolyExp
+	if err := poly.ParseParams(sexp, params); err != nil {
+		return PolyExp{}, fmt.Errorf("error parsing user input as polynomial: %s", err)
+	}
+	return poly, nil
+}
+
+func parseSessionPoly(session *Session, raw string) (PolyExp, error) {
+	poly, err := session.Parse(raw)
+	if err != nil {
+		return PolyExp{}, fmt.Errorf("error parsing user input: %s", err)
+	}
+	return poly, nil
+}
+
+var paramFlag = &cli.StringSliceFlag{
+	Name:  "param",
+	Usage: "declare a symbol a parameter standing for a constant, repeat for each parameter, other symbols are variables",
+}
+
+// Parameters declared with --param
+func params(cctx *cli.Context) []Symbol {
+	var ret []Symbol
+	for _, a := range cctx.StringSlice("param") {
+		ret = append(ret, Symbol(a))
+	}
+	return ret
+}
+
+// Rainbow formatted expression, plain if formatting fails
+func pretty(poly PolyExp) string {
+	raw := poly.ToSExp().String()
+	prettyString, err := RainbowParens(raw, Rainbow)
+	if err != nil {
+		return raw
+	}
+	return prettyString
+}
+
 var ddxCmd = &cli.Command{
 	Name:        "d/dx",
 	Description: "Take derivative in bound variable x",
-	Usage:       "d/dx <poly expr>",
+	Usage:       "d/dx [--explain] [--param a] <poly expr>",
+	Flags: []cli.Flag{
+		paramFlag,
+		explainFlag,
+	},
 	Action: func(cctx *cli.Context) error {
 		if cctx.Args().Len() != 1 {
 			return fmt.Errorf("invalid arguments to d/dx")
 		}
-		var sexp SExp
-		if err := sexp.Parse(cctx.Args().First()); err != nil {
-			return fmt.Errorf("error parsing user input as sexp: %s", err)
-		}
-		var poly PolyExp
-		if err := poly.Parse(sexp); err != nil {
-			return fmt.Errorf("error parsing user input as polynomial: %s", err)
+		poly, err := parsePoly(cctx.Args().First(), params(cctx))
+		if err != nil {
+			return err
 		}
 
-		d, err := Differentiate(Symbol("x"), poly)
+		var tr *Trace
+		if cctx.Bool("explain") {
+			tr = new(Trace)
+		}
+		d, err := DifferentiateTrace(Symbol("x"), poly, tr)
 		if err != nil {
 			return fmt.Errorf("error taking derivative: %s", err)
 		}
+		if tr != nil {
+			fmt.Printf("%s", tr.String())
+		}
 		prettyString, err := RainbowParens(d.ToSExp().String(), Rainbow)
 		if err != nil {
 			fmt.Printf("Error formatting output: %s", err)
@@ -113,27 +360,79 @@ var ddxCmd = &cli.Command{
 	},
 }
 
+var explainFlag = &cli.BoolFlag{
+	Name:  "explain",
+	Usage: "print each rule applied on the way to the result",
+}
+
 var simplifyCmd = &cli.Command{
 	Name:        "simplify",
-	Description: "Run polynomial simplification",
-	Usage:       "simplify <poly expr>",
+	Description: "Run polynomial simplification followed by any rules loaded from a file",
+	Usage:       "simplify [--explain] [--expand] [--param a] [--rules file] [--strategy bottomup|topdown|root] [--order ascending|descending|lex|grlex|grevlex] <poly expr>",
+	Flags: []cli.Flag{
+		paramFlag,
+		explainFlag,
+		&cli.BoolFlag{
+			Name:  "expand",
+			Usage: "multiply out products and powers of sums",
+		},
+		&cli.StringFlag{
+			Name:  "rules",
+			Usage: "file of extra rewrite rules, one \"<pattern> => <template>\" per line",
+		},
+		&cli.StringFlag{
+			Name:  "strategy",
+			Usage: "where extra rules are applied: bottomup, topdown or root",
+			Value: BottomUp.String(),
+		},
+		&cli.StringFlag{
+			Name:  "order",
+			Usage: "order of terms: ascending, descending, lex, grlex or grevlex",
+			Value: Ascending.String(),
+		},
+	},
 	Action: func(cctx *cli.Context) error {
 		if cctx.Args().Len() != 1 {
 			return fmt.Errorf("invalid arguments to simplify")
 		}
-		var sexp SExp
-		if err := sexp.Parse(cctx.Args().First()); err != nil {
-			return fmt.Errorf("error parsing user input as sexp: %s", err)
+		poly, err := parsePoly(cctx.Args().First(), params(cctx))
+		if err != nil {
+			return err
+		}
+		var tr *Trace
+		if cctx.Bool("explain") {
+			tr = new(Trace)
 		}
-		var poly PolyExp
-		if err := poly.Parse(sexp); err != nil {
-			return fmt.Errorf("error parsing user input as polynomial: %s", err)
+		order, err := ParseTermOrder(cctx.String("order"))
+		if err != nil {
+			return err
 		}
 		// simplify
-		s, err := Simplify(poly)
+		sets := SimplifyRuleSetsOrder(order)
+		for i := range sets {
+			if sets[i].Name == "Expand" {
+				sets[i].Disabled = !cctx.Bool("expand")
+			}
+		}
+		simplified, err := ApplyRuleSetsTrace(poly, tr, sets...)
 		if err != nil {
 			return fmt.Errorf("error simplifying expression %s: %s", poly.ToSExp().String(), err)
 		}
+		s := &simplified
+		if path := cctx.String("rules"); path != "" {
+			rules, err := loadRules(path, cctx.String("strategy"))
+			if err != nil {
+				return err
+			}
+			applied, err := rules.ApplyTrace(*s, tr)
+			if err != nil {
+				return fmt.Errorf("error applying rules to %s: %s", s.ToSExp().String(), err)
+			}
+			s = &applied
+		}
+		if tr != nil {
+			fmt.Printf("%s", tr.String())
+		}
 
 		prettyString, err := RainbowParens(s.ToSExp().String(), Rainbow)
 		if err != nil {
@@ -143,3 +442,270 @@ var simplifyCmd = &cli.Command{
 		return nil
 	},
 }
+
+var evalCmd = &cli.Command{
+	Name:        "eval",
+	Description: "Evaluate expression at a point",
+	Usage:       "eval --at x=2 --at a=-1/2 <poly expr>",
+	Flags: []cli.Flag{
+		paramFlag,
+		&cli.StringSliceFlag{
+			Name:  "at",
+			Usage: "symbol=value binding, repeat for each symbol",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return fmt.Errorf("invalid arguments to eval")
+		}
+		env := make(map[Symbol]float64)
+		for _, binding := range cctx.StringSlice("at") {
+			sym, val, ok := strings.Cut(binding, "=")
+			if !ok || !IsSymbol(sym) {
+				return fmt.Errorf("invalid binding %s, expected symbol=value", binding)
+			}
+			v, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				r, rerr := ParseRational(val)
+				if rerr != nil {
+					return fmt.Errorf("invalid value in binding %s: %s", binding, err)
+				}
+				v = r.Float64()
+			}
+			env[Symbol(sym)] = v
+		}
+		poly, err := parsePoly(cctx.Args().First(), params(cctx))
+		if err != nil {
+			return err
+		}
+		v, err := Evaluate(poly, env)
+		if err != nil {
+			return fmt.Errorf("error evaluating expression: %s", err)
+		}
+		fmt.Printf("%v\n", v)
+		return nil
+	},
+}
+
+var equivCmd = &cli.Command{
+	Name:        "equiv",
+	Description: "Check two expressions denote the same function, exiting with an error and a counterexample point when they differ",
+	Usage:       "equiv <poly expr> <poly expr>",
+	Flags: []cli.Flag{
+		paramFlag,
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 {
+			return fmt.Errorf("invalid arguments to equiv")
+		}
+		a, err := parsePoly(cctx.Args().Get(0), params(cctx))
+		if err != nil {
+			return err
+		}
+		b, err := parsePoly(cctx.Args().Get(1), params(cctx))
+		if err != nil {
+			return err
+		}
+		eq, err := Equivalent(a, b)
+		if err != nil {
+			return fmt.Errorf("error checking equivalence: %s", err)
+		}
+		if !eq.Equivalent {
+			return fmt.Errorf("%s", eq.String())
+		}
+		fmt.Printf("%s\n", eq.String())
+		return nil
+	},
+}
+
+var factorCmd = &cli.Command{
+	Name:        "factor",
+	Description: "Factor a polynomial in one variable with rational coefficients over the integers",
+	Usage:       "factor <poly expr>",
+	Flags: []cli.Flag{
+		paramFlag,
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return fmt.Errorf("invalid arguments to factor")
+		}
+		poly, err := parsePoly(cctx.Args().First(), params(cctx))
+		if err != nil {
+			return err
+		}
+		f, err := Factor(poly)
+		if err != nil {
+			return fmt.Errorf("error factoring expression: %s", err)
+		}
+		fmt.Printf("%s\n", pretty(*f))
+		return nil
+	},
+}
+
+var collectCmd = &cli.Command{
+	Name:        "collect",
+	Description: "Expand and group terms by powers of a variable with coefficients in the other symbols",
+	Usage:       "collect <symbol> <poly expr>",
+	Flags: []cli.Flag{
+		paramFlag,
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 || !IsSymbol(cctx.Args().Get(0)) {
+			return fmt.Errorf("invalid arguments to collect")
+		}
+		poly, err := parsePoly(cctx.Args().Get(1), params(cctx))
+		if err != nil {
+			return err
+		}
+		c, err := Collect(poly, Symbol(cctx.Args().Get(0)))
+		if err != nil {
+			return fmt.Errorf("error collecting expression: %s", err)
+		}
+		fmt.Printf("%s\n", pretty(*c))
+		return nil
+	},
+}
+
+var hornerCmd = &cli.Command{
+	Name:        "horner",
+	Description: "Write a polynomial in one variable with rational coefficients in nested Horner form",
+	Usage:       "horner <symbol> <poly expr>",
+	Flags: []cli.Flag{
+		paramFlag,
+		&cli.BoolFlag{
+			Name:  "infix",
+			Usage: "print as an infix expression for code generation",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 || !IsSymbol(cctx.Args().Get(0)) {
+			return fmt.Errorf("invalid arguments to horner")
+		}
+		v := Symbol(cctx.Args().Get(0))
+		poly, err := parsePoly(cctx.Args().Get(1), params(cctx))
+		if err != nil {
+			return err
+		}
+		if cctx.Bool("infix") {
+			infix, err := HornerString(poly, v)
+			if err != nil {
+				return fmt.Errorf("error writing expression in Horner form: %s", err)
+			}
+			fmt.Printf("%s\n", infix)
+			return nil
+		}
+		h, err := Horner(poly, v)
+		if err != nil {
+			return fmt.Errorf("error writing expression in Horner form: %s", err)
+		}
+		fmt.Printf("%s\n", pretty(*h))
+		return nil
+	},
+}
+
+var substCmd = &cli.Command{
+	Name:        "subst",
+	Description: "Replace symbols with expressions, simultaneously",
+	Usage:       "subst [--simplify] --with \"x=( + ( ^ t 1 ) 1 )\" <poly expr>",
+	Flags: []cli.Flag{
+		paramFlag,
+		&cli.StringSliceFlag{
+			Name:  "with",
+			Usage: "symbol=<poly expr> replacement, repeat for each symbol",
+		},
+		&cli.BoolFlag{
+			Name:  "simplify",
+			Usage: "simplify the result",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return fmt.Errorf("invalid arguments to subst")
+		}
+		with := make(map[Symbol]PolyExp)
+		for _, binding := range cctx.StringSlice("with") {
+			sym, raw, ok := strings.Cut(binding, "=")
+			sym = strings.TrimSpace(sym)
+			if !ok || !IsSymbol(sym) {
+				return fmt.Errorf("invalid replacement %s, expected symbol=<poly expr>", binding)
+			}
+			g, err := parsePoly(raw, params(cctx))
+			if err != nil {
+				return fmt.Errorf("invalid replacement for %s: %s", sym, err)
+			}
+			with[Symbol(sym)] = g
+		}
+		poly, err := parsePoly(cctx.Args().First(), params(cctx))
+		if err != nil {
+			return err
+		}
+		s, err := Substitute(poly, with)
+		if err != nil {
+			return fmt.Errorf("error substituting: %s", err)
+		}
+		if cctx.Bool("simplify") {
+			simplified, err := Simplify(*s)
+			if err != nil {
+				return fmt.Errorf("error simplifying expression %s: %s", s.ToSExp().String(), err)
+			}
+			s = simplified
+		}
+		fmt.Printf("%s\n", pretty(*s))
+		return nil
+	},
+}
+
+var rootsCmd = &cli.Command{
+	Name:        "roots",
+	Description: "Find the real and complex roots of a polynomial in one variable with rational coefficients, exactly up to degree 2 and for rational roots",
+	Usage:       "roots [--real] <poly expr>",
+	Flags: []cli.Flag{
+		paramFlag,
+		&cli.BoolFlag{
+			Name:  "real",
+			Usage: "only print real roots",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return fmt.Errorf("invalid arguments to roots")
+		}
+		poly, err := parsePoly(cctx.Args().First(), params(cctx))
+		if err != nil {
+			return err
+		}
+		rs, err := Roots(poly)
+		if err != nil {
+			return fmt.Errorf("error finding roots: %s", err)
+		}
+		for _, r := range rs {
+			if cctx.Bool("real") && !r.IsReal() {
+				continue
+			}
+			fmt.Printf("%s\n", r.String())
+		}
+		return nil
+	},
+}
+
+// Load a rule set from a rules file
+func loadRules(path string, strategy string) (*RuleSet, error) {
+	st, err := ParseStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening rules file: %s", err)
+	}
+	defer f.Close()
+	rules, err := ParseRules(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing rules file %s: %s", path, err)
+	}
+	return &RuleSet{
+		Name:     path,
+		Rules:    rules,
+		Strategy: st,
+	}, nil
+}
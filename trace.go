@@ -0,0 +1,125 @@
+package symdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// One rule application of a derivation
+type Step struct {
+	Rule   string
+	Before PolyExp
+	After  PolyExp
+}
+
+func (s Step) String() string {
+	return fmt.Sprintf("%s ==> %s [%s]", s.Before.ToSExp().String(), s.After.ToSExp().String(), s.Rule)
+}
+
+// Steps of a derivation in the order they were taken.  A nil trace records
+// nothing so tracing can be threaded through optionally.
+type Trace struct {
+	Steps []Step
+}
+
+func (tr *Trace) record(rule string, before, after PolyExp) {
+	if tr == nil {
+		return
+	}
+	tr.Steps = append(tr.Steps, Step{Rule: rule, Before: before, After: after})
+}
+
+// Record a step whose result is not known yet so it precedes the steps
+// taken to compute it, completed by end
+func (tr *Trace) begin(rule string, before PolyExp) int {
+	if tr == nil {
+		return -1
+	}
+	tr.Steps = append(tr.Steps, Step{Rule: rule, Before: before})
+	return len(tr.Steps) - 1
+}
+
+func (tr *Trace) end(i int, after PolyExp) {
+	if tr == nil {
+		return
+	}
+	tr.Steps[i].After = after
+}
+
+// Drop step i and the steps after it, i.e. when the computation begun at
+// step i fails or changes nothing
+func (tr *Trace) truncate(i int) {
+	if tr == nil {
+		return
+	}
+	tr.Steps = tr.Steps[:i]
+}
+
+// Numbered steps, one per line
+func (tr *Trace) String() string {
+	var b strings.Builder
+	for i, s := range tr.Steps {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, s.String())
+	}
+	return b.String()
+}
+
+// Differentiate recording the rule used for every differentiated sub
+// expression, outermost first, i.e. the sum rule on a sum before the power
+// rule on each of its monomials
+func DifferentiateTrace(v Symbol, exp PolyExp, tr *Trace) (*PolyExp, error) {
+	return differentiate(v, exp, tr)
+}
+
+// Simplify recording each rewrite rule application, i.e. each coefficient
+// distributed, and each pass that changes the expression followed by the
+// rewrites it made, i.e. each group of like terms folded
+func SimplifyTrace(poly PolyExp, tr *Trace) (*PolyExp, error) {
+	ret, err := ApplyRuleSetsTrace(poly, tr, simplifyRules...)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func (c *ConstantExp) derivativeRule(v Symbol) string {
+	return "constant rule"
+}
+
+func (a *ParamExp) derivativeRule(v Symbol) string {
+	return "constant rule"
+}
+
+func (m *MonomialExp) derivativeRule(v Symbol) string {
+	return "power rule"
+}
+
+func (p *ProductExp) derivativeRule(v Symbol) string {
+	if len(p.fs) > 0 {
+		return "product rule"
+	}
+	return "constant multiple rule"
+}
+
+func (pow *PowerExp) derivativeRule(v Symbol) string {
+	if pow.isCoefficient() {
+		return "constant rule"
+	}
+	return "chain rule"
+}
+
+func (sum *SumExp) derivativeRule(v Symbol) string {
+	return "sum rule"
+}
+
+func (a *AbsExp) derivativeRule(v Symbol) string {
+	return "absolute value rule"
+}
+
+func (sgn *SignExp) derivativeRule(v Symbol) string {
+	return "sign rule"
+}
+
+func (pw *PiecewiseExp) derivativeRule(v Symbol) string {
+	return "piecewise rule"
+}
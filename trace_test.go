@@ -0,0 +1,124 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+func stepStrings(tr *Trace) []string {
+	ret := make([]string, len(tr.Steps))
+	for i, s := range tr.Steps {
+		ret[i] = s.String()
+	}
+	return ret
+}
+
+func TestDifferentiateTrace(t *testing.T) {
+	poly := polyFromString(t, "( + ( * 3 ( ^ x 5 ) ) ( * a ( ^ x 1 ) ( sqrt ( ^ x 1 ) ) ) x a )", "a")
+	var tr Trace
+	d, err := DifferentiateTrace("x", poly, &tr)
+	require.NoError(t, err)
+	expected, err := Differentiate("x", poly)
+	require.NoError(t, err)
+	assert.Equal(t, expected, d)
+
+	var rules []string
+	for _, s := range tr.Steps {
+		rules = append(rules, s.Rule)
+	}
+	assert.Equal(t, []string{
+		"sum rule",
+		"constant multiple rule",
+		"power rule",
+		"product rule",
+		"power rule",
+		"chain rule",
+		"power rule",
+		"power rule",
+		"constant rule",
+	}, rules)
+	assert.Equal(t, "( ^ x 5 ) ==> ( * 5 ( ^ x 4 ) ) [power rule]", tr.Steps[2].String())
+	assert.Equal(t, poly, tr.Steps[0].Before)
+	assert.Equal(t, *d, tr.Steps[0].After)
+
+	// nil traces record nothing
+	d, err = DifferentiateTrace("x", poly, nil)
+	require.NoError(t, err)
+	assert.Equal(t, expected, d)
+
+	// failed derivations leave no partial steps behind
+	n := len(tr.Steps)
+	_, err = DifferentiateTrace("x", polyFromString(t, "( + ( ^ x 2 ) ( * x ( ^ x 2 ) ) )", "x"), &tr)
+	assert.Error(t, err)
+	assert.Len(t, tr.Steps, n)
+}
+
+func TestDifferentiateTracePiecewise(t *testing.T) {
+	var tr Trace
+	_, err := DifferentiateTrace("x", polyFromString(t, "( + "+hinge+" ( sign ( ^ x 1 ) ) )"), &tr)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		hinge + " ==> ( piecewise ( ( < ( ^ x 1 ) 1 ) ( + 0 ( * -1 ( * 1 ( ^ x 0 ) ) ) ) ) ( else 0 ) ) [piecewise rule]",
+		"( + 1 ( * -1 ( ^ x 1 ) ) ) ==> ( + 0 ( * -1 ( * 1 ( ^ x 0 ) ) ) ) [sum rule]",
+		"1 ==> 0 [constant rule]",
+		"( * -1 ( ^ x 1 ) ) ==> ( * -1 ( * 1 ( ^ x 0 ) ) ) [constant multiple rule]",
+		"( ^ x 1 ) ==> ( * 1 ( ^ x 0 ) ) [power rule]",
+		"0 ==> 0 [constant rule]",
+		"( sign ( ^ x 1 ) ) ==> ( piecewise ( ( < ( ^ x 1 ) 0 ) 0 ) ( ( > ( ^ x 1 ) 0 ) 0 ) ) [sign rule]",
+	}, stepStrings(&tr)[1:])
+}
+
+func TestSimplifyTrace(t *testing.T) {
+	poly := polyFromString(t, "( * 3 ( + ( ^ x 2 ) ( ^ x 2 ) 0 ) )")
+	var tr Trace
+	s, err := SimplifyTrace(poly, &tr)
+	require.NoError(t, err)
+	assert.Equal(t, "( * 6 ( ^ x 2 ) )", s.ToSExp().String())
+	assert.Equal(t, []string{
+		"( * 3 ( + ( ^ x 2 ) ( ^ x 2 ) 0 ) ) ==> ( + ( * 3 ( ^ x 2 ) ) ( * 3 ( + ( ^ x 2 ) 0 ) ) ) [( * ?c ( + ?a ?b ?d ??rest ) ) => ( + ( * ?c ?a ) ( * ?c ( + ?b ?d ??rest ) ) )]",
+		"( * 3 ( + ( ^ x 2 ) 0 ) ) ==> ( + ( * 3 ( ^ x 2 ) ) ( * 3 0 ) ) [( * ?c ( + ?a ?b ) ) => ( + ( * ?c ?a ) ( * ?c ?b ) )]",
+		"( * 3 0 ) ==> 0 [( * ?c 0 ) => 0]",
+		"( + ( * 3 ( ^ x 2 ) ) ( + ( * 3 ( ^ x 2 ) ) 0 ) ) ==> ( + ( * 3 ( ^ x 2 ) ) ( * 3 ( ^ x 2 ) ) 0 ) [( + ??a ( + ??b ) ??c ) => ( + ??a ??b ??c )]",
+		"( + ( * 3 ( ^ x 2 ) ) ( * 3 ( ^ x 2 ) ) 0 ) ==> ( + ( * 6 ( ^ x 2 ) ) 0 ) [Fold]",
+		"( + ( * 3 ( ^ x 2 ) ) ( * 3 ( ^ x 2 ) ) ) ==> ( * 6 ( ^ x 2 ) ) [fold]",
+		"( + ( * 6 ( ^ x 2 ) ) 0 ) ==> ( * 6 ( ^ x 2 ) ) [( + ?a 0 ) => ?a]",
+	}, stepStrings(&tr))
+	assert.Equal(t, "1. "+tr.Steps[0].String()+"\n2. "+tr.Steps[1].String()+"\n", (&Trace{Steps: tr.Steps[:2]}).String())
+
+	// each coefficient distributed and each group of like terms folded is a step
+	tr = Trace{}
+	_, err = SimplifyTrace(polyFromString(t, "( + ( * 2 ( + ( ^ x 2 ) 1 ) ) ( ^ x 2 ) ( * 3 ( ^ x 1 ) ) 4 )"), &tr)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"( * 2 ( + ( ^ x 2 ) 1 ) ) ==> ( + ( * 2 ( ^ x 2 ) ) ( * 2 1 ) ) [( * ?c ( + ?a ?b ) ) => ( + ( * ?c ?a ) ( * ?c ?b ) )]",
+		"( * 2 1 ) ==> 2 [( * ?c 1 ) => ?c]",
+		"( + ( + ( * 2 ( ^ x 2 ) ) 2 ) ( ^ x 2 ) ( * 3 ( ^ x 1 ) ) 4 ) ==> ( + ( * 2 ( ^ x 2 ) ) 2 ( ^ x 2 ) ( * 3 ( ^ x 1 ) ) 4 ) [( + ??a ( + ??b ) ??c ) => ( + ??a ??b ??c )]",
+	}, stepStrings(&tr)[:3])
+	var folds []string
+	for _, s := range tr.Steps {
+		if s.Rule == "fold" {
+			folds = append(folds, s.String())
+		}
+	}
+	assert.Equal(t, []string{
+		"( + ( * 2 ( ^ x 2 ) ) ( ^ x 2 ) ) ==> ( * 3 ( ^ x 2 ) ) [fold]",
+		"( + 2 4 ) ==> 6 [fold]",
+	}, folds)
+
+	// rules applied below the root record the rewritten sub expression
+	tr = Trace{}
+	rs := RuleSet{Name: "identities", Rules: []Rule{ruleFromString(t, "( * 1 ?e ) => ?e")}}
+	_, err = rs.ApplyTrace(polyFromString(t, "( abs ( * 1 ( ^ x 1 ) ) )"), &tr)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"( * 1 ( ^ x 1 ) ) ==> ( ^ x 1 ) [( * 1 ?e ) => ?e]"}, stepStrings(&tr))
+
+	session := NewSession()
+	require.NoError(t, session.Disable("DropZero"))
+	tr = Trace{}
+	_, err = session.SimplifyTrace(poly, &tr)
+	require.NoError(t, err)
+	assert.Len(t, tr.Steps, 6)
+}
@@ -0,0 +1,248 @@
+package symdiff
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Bound on the degree of dense univariate polynomials, their coefficients are
+// allocated up to the degree
+const MaxDegree = 1 << 16
+
+// Dense univariate polynomial with rational coefficients, lowest degree first
+// Invariant: no trailing zero coefficients, the zero polynomial is empty
+type upoly []Rational
+
+// Polynomial in a single variable with rational coefficients and natural
+// exponents, after expanding.  The symbol is empty for constants.
+func toUnivariate(exp PolyExp) (Symbol, upoly, error) {
+	expanded, err := Expand(exp)
+	if err != nil {
+		return "", nil, err
+	}
+	var x Symbol
+	var ret upoly
+	for _, t := range Flatten(*expanded) {
+		c := Integer(1)
+		if t.IsProduct() {
+			l, fs := t.product().Term()
+			if !l.IsConstant() || len(fs) != 1 {
+				return "", nil, fmt.Errorf("term %s is not a rational multiple of a power of one variable", t.ToSExp().String())
+			}
+			c, t = l.constant().c, fs[0]
+		}
+		n := 0
+		switch {
+		case t.IsConstant():
+			c = c.Mul(t.constant().c)
+		case t.IsMon() && t.mon().e == nil && t.mon().n.IsInteger() && t.mon().n.Sign() >= 0:
+			if x != "" && x != t.mon().x {
+				return "", nil, fmt.Errorf("polynomial in more than one variable, %s and %s", x, t.mon().x)
+			}
+			if !t.mon().n.IsInt() || t.mon().n.Int() > MaxDegree {
+				return "", nil, fmt.Errorf("degree %s of term %s exceeds the maximum degree %d", t.mon().n.String(), t.ToSExp().String(), MaxDegree)
+			}
+			x, n = t.mon().x, t.mon().n.Int()
+		default:
+			return "", nil, fmt.Errorf("term %s is not a rational multiple of a natural power of a variable", t.ToSExp().String())
+		}
+		for len(ret) <= n {
+			ret = append(ret, Integer(0))
+		}
+		ret[n] = ret[n].Add(c)
+	}
+	return x, ret.trim(), nil
+}
+
+// Expression in x with terms ordered as by Simplify, ascending degree with the
+// constant last
+func (p upoly) toPolyExp(x Symbol) PolyExp {
+	terms := make([]PolyExp, 0, len(p))
+	for n := 1; n < len(p); n++ {
+		if p[n].IsZero() {
+			continue
+		}
+		mon := PolyExp{e: &MonomialExp{x: x, n: Integer(n)}}
+		if p[n].Equal(Integer(1)) {
+			terms = append(terms, mon)
+			continue
+		}
+		terms = append(terms, PolyExp{e: &ProductExp{l: &PolyExp{e: &ConstantExp{c: p[n]}}, r: &mon}})
+	}
+	if len(p) > 0 && !p[0].IsZero() {
+		terms = append(terms, PolyExp{e: &ConstantExp{c: p[0]}})
+	}
+	if len(terms) == 0 {
+		return Zero()
+	}
+	return *Join(terms)
+}
+
+func (p upoly) trim() upoly {
+	for len(p) > 0 && p[len(p)-1].IsZero() {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// Degree, -1 for the zero polynomial
+func (p upoly) degree() int {
+	return len(p) - 1
+}
+
+// Leading coefficient, 0 for the zero polynomial
+func (p upoly) lc() Rational {
+	if len(p) == 0 {
+		return Integer(0)
+	}
+	return p[len(p)-1]
+}
+
+func (p upoly) add(q upoly) upoly {
+	n := len(p)
+	if len(q) > n {
+		n = len(q)
+	}
+	ret := make(upoly, n)
+	for i := range ret {
+		ret[i] = Integer(0)
+		if i < len(p) {
+			ret[i] = ret[i].Add(p[i])
+		}
+		if i < len(q) {
+			ret[i] = ret[i].Add(q[i])
+		}
+	}
+	return ret.trim()
+}
+
+func (p upoly) sub(q upoly) upoly {
+	return p.add(q.scale(Integer(-1)))
+}
+
+func (p upoly) scale(c Rational) upoly {
+	ret := make(upoly, len(p))
+	for i := range p {
+		ret[i] = p[i].Mul(c)
+	}
+	return ret.trim()
+}
+
+func (p upoly) mul(q upoly) upoly {
+	if len(p) == 0 || len(q) == 0 {
+		return nil
+	}
+	ret := make(upoly, len(p)+len(q)-1)
+	for i := range ret {
+		ret[i] = Integer(0)
+	}
+	for i := range p {
+		for j := range q {
+			ret[i+j] = ret[i+j].Add(p[i].Mul(q[j]))
+		}
+	}
+	return ret.trim()
+}
+
+// Quotient and remainder of division by non zero q
+func (p upoly) divmod(q upoly) (upoly, upoly, error) {
+	if len(q) == 0 {
+		return nil, nil, fmt.Errorf("polynomial division by zero")
+	}
+	r := append(upoly{}, p...)
+	if len(r) < len(q) {
+		return nil, r, nil
+	}
+	quo := make(upoly, len(r)-len(q)+1)
+	for i := range quo {
+		quo[i] = Integer(0)
+	}
+	for len(r) >= len(q) {
+		k := len(r) - len(q)
+		c, err := r.lc().Div(q.lc())
+		if err != nil {
+			return nil, nil, err
+		}
+		quo[k] = c
+		for i := range q {
+			r[i+k] = r[i+k].Sub(c.Mul(q[i]))
+		}
+		// the leading term cancels exactly
+		r = r[:len(r)-1].trim()
+	}
+	return quo.trim(), r, nil
+}
+
+// Scaled to leading coefficient 1, zero stays zero
+func (p upoly) monic() (upoly, error) {
+	if len(p) == 0 {
+		return p, nil
+	}
+	inv, err := Integer(1).Div(p.lc())
+	if err != nil {
+		return nil, err
+	}
+	return p.scale(inv), nil
+}
+
+// Monic greatest common divisor, zero only when both are zero
+func (p upoly) gcd(q upoly) (upoly, error) {
+	for len(q) > 0 {
+		_, r, err := p.divmod(q)
+		if err != nil {
+			return nil, err
+		}
+		p, q = q, r
+	}
+	return p.monic()
+}
+
+func (p upoly) derivative() upoly {
+	if len(p) < 2 {
+		return nil
+	}
+	ret := make(upoly, len(p)-1)
+	for i := 1; i < len(p); i++ {
+		ret[i-1] = p[i].Mul(Integer(i))
+	}
+	return ret.trim()
+}
+
+// Value at x by Horner's rule
+func (p upoly) eval(x Rational) Rational {
+	ret := Integer(0)
+	for i := len(p) - 1; i >= 0; i-- {
+		ret = ret.Mul(x).Add(p[i])
+	}
+	return ret
+}
+
+// Rational content c and primitive part q with coprime integer coefficients
+// and positive leading coefficient, p = c q
+func (p upoly) primitive() (Rational, upoly) {
+	if len(p) == 0 {
+		return Integer(0), nil
+	}
+	// l the lcm of the denominators, g the gcd of the numerators over l
+	l := big.NewInt(1)
+	for _, c := range p {
+		_, den := c.Frac()
+		k := new(big.Int).GCD(nil, nil, l, den)
+		l.Mul(l, den.Quo(den, k))
+	}
+	nums := make([]*big.Int, len(p))
+	g := new(big.Int)
+	for i, c := range p {
+		num, den := c.Frac()
+		nums[i] = num.Mul(num, den.Quo(l, den))
+		g.GCD(nil, nil, g, new(big.Int).Abs(num))
+	}
+	if p.lc().Sign() < 0 {
+		g.Neg(g)
+	}
+	ret := make(upoly, len(p))
+	for i := range nums {
+		ret[i] = intRational(nums[i].Quo(nums[i], g))
+	}
+	return fromRat(new(big.Rat).SetFrac(g, l)), ret
+}
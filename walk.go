@@ -0,0 +1,243 @@
+package symdiff
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+   Traversal of expression trees for writing custom passes
+
+   Walk and Inspect visit every node top down, Rewrite replaces nodes bottom up.
+   The children of a node are the expressions it is built from in printed
+   order: exponents of monomials, coefficients and factors of products, bases
+   of powers, terms of sums, arguments of abs and sign, and for piecewise
+   expressions both sides of each condition followed by its piece.
+
+   Count monomials
+
+     monomials := 0
+     Inspect(poly, func(e PolyExp) bool {
+         if e.IsMon() {
+             monomials++
+         }
+         return true
+     })
+
+   Rename x to y
+
+     renamed, err := Rewrite(poly, func(e PolyExp) (PolyExp, bool) {
+         if m, err := e.Mon(); err == nil {
+             if x, _ := m.Term(); x == "x" {
+                 y, err := SymbolicPow("y", m.Exponent())
+                 return y, err == nil
+             }
+         }
+         return e, false
+     })
+*/
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children of
+// the node with w.
+type Visitor interface {
+	Visit(exp PolyExp) (w Visitor)
+}
+
+// Traverse exp in depth first order, calling v.Visit on each node before
+// its children
+// Invariant: expression is checked as internally valid
+func Walk(exp PolyExp, v Visitor) {
+	if v = v.Visit(exp); v == nil {
+		return
+	}
+	for _, c := range exp.e.children() {
+		Walk(c, v)
+	}
+}
+
+type inspector func(PolyExp) bool
+
+func (f inspector) Visit(exp PolyExp) Visitor {
+	if f(exp) {
+		return f
+	}
+	return nil
+}
+
+// Traverse exp in depth first order calling f on each node, children are
+// skipped when f returns false
+func Inspect(exp PolyExp, f func(PolyExp) bool) {
+	Walk(exp, inspector(f))
+}
+
+// Sorted symbols of the parameters
+func (p *PolyExp) Params() []Symbol {
+	if p.check() != nil {
+		return nil
+	}
+	seen := make(map[Symbol]bool)
+	Inspect(*p, func(e PolyExp) bool {
+		if e.IsParam() {
+			seen[e.param().a] = true
+		}
+		return true
+	})
+	ret := make([]Symbol, 0, len(seen))
+	for s := range seen {
+		ret = append(ret, s)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// Rebuild exp bottom up, children are rewritten first and f is then called
+// on the node with its rewritten children.  When f reports true its result
+// replaces the node, replacements are not rewritten again.  Untouched
+// subtrees are shared with exp.
+// Rebuilt nodes are validated as when parsing, i.e. rewriting the
+// coefficient of a product into a monomial is an error.
+func Rewrite(exp PolyExp, f func(PolyExp) (PolyExp, bool)) (PolyExp, error) {
+	ret, _, err := rewrite(exp, f)
+	return ret, err
+}
+
+func rewrite(exp PolyExp, f func(PolyExp) (PolyExp, bool)) (PolyExp, bool, error) {
+	if err := exp.check(); err != nil {
+		return PolyExp{}, false, err
+	}
+	children := exp.e.children()
+	changed := false
+	for i := range children {
+		c, ok, err := rewrite(children[i], f)
+		if err != nil {
+			return PolyExp{}, false, err
+		}
+		children[i] = c
+		changed = changed || ok
+	}
+	node := exp
+	if changed {
+		e, err := exp.e.rebuild(children)
+		if err != nil {
+			return PolyExp{}, false, fmt.Errorf("%s, failed to rebuild %s", err, exp.ToSExp().String())
+		}
+		node = PolyExp{e: e}
+	}
+	replacement, ok := f(node)
+	if !ok {
+		return node, changed, nil
+	}
+	if err := replacement.check(); err != nil {
+		return PolyExp{}, false, fmt.Errorf("%s, invalid replacement for %s", err, node.ToSExp().String())
+	}
+	return replacement, true, nil
+}
+
+func (c *ConstantExp) children() []PolyExp {
+	return nil
+}
+
+func (c *ConstantExp) rebuild(children []PolyExp) (Expr, error) {
+	return c, nil
+}
+
+func (a *ParamExp) children() []PolyExp {
+	return nil
+}
+
+func (a *ParamExp) rebuild(children []PolyExp) (Expr, error) {
+	return a, nil
+}
+
+func (m *MonomialExp) children() []PolyExp {
+	if m.e == nil {
+		return nil
+	}
+	return []PolyExp{*m.e}
+}
+
+func (m *MonomialExp) rebuild(children []PolyExp) (Expr, error) {
+	if m.e == nil {
+		return m, nil
+	}
+	ret, err := SymbolicPow(m.x, children[0])
+	return ret.e, err
+}
+
+func (p *ProductExp) children() []PolyExp {
+	l, factors := p.Term()
+	return append([]PolyExp{l}, factors...)
+}
+
+func (p *ProductExp) rebuild(children []PolyExp) (Expr, error) {
+	if !children[0].isCoefficient() {
+		return nil, fmt.Errorf("coefficient %s of product may not contain monomials", children[0].ToSExp().String())
+	}
+	ret := ProductExp{l: &children[0], r: &children[1]}
+	if len(children) > 2 {
+		ret.fs = children[2:]
+	}
+	return &ret, nil
+}
+
+func (pow *PowerExp) children() []PolyExp {
+	return []PolyExp{*pow.b}
+}
+
+func (pow *PowerExp) rebuild(children []PolyExp) (Expr, error) {
+	ret, err := Raise(children[0], pow.n)
+	return ret.e, err
+}
+
+func (sum *SumExp) children() []PolyExp {
+	return sum.Term()
+}
+
+func (sum *SumExp) rebuild(children []PolyExp) (Expr, error) {
+	return &SumExp{ps: children}, nil
+}
+
+func (a *AbsExp) children() []PolyExp {
+	return []PolyExp{*a.e}
+}
+
+func (a *AbsExp) rebuild(children []PolyExp) (Expr, error) {
+	return &AbsExp{e: &children[0]}, nil
+}
+
+func (sgn *SignExp) children() []PolyExp {
+	return []PolyExp{*sgn.e}
+}
+
+func (sgn *SignExp) rebuild(children []PolyExp) (Expr, error) {
+	return &SignExp{e: &children[0]}, nil
+}
+
+func (pw *PiecewiseExp) children() []PolyExp {
+	ret := make([]PolyExp, 0, 3*len(pw.cs))
+	for i, c := range pw.cs {
+		if !c.IsElse() {
+			ret = append(ret, *c.l, *c.r)
+		}
+		ret = append(ret, pw.es[i])
+	}
+	return ret
+}
+
+func (pw *PiecewiseExp) rebuild(children []PolyExp) (Expr, error) {
+	ret := PiecewiseExp{
+		cs: make([]Condition, len(pw.cs)),
+		es: make([]PolyExp, len(pw.es)),
+	}
+	for i, c := range pw.cs {
+		if !c.IsElse() {
+			c.l, c.r = &children[0], &children[1]
+			children = children[2:]
+		}
+		ret.cs[i] = c
+		ret.es[i] = children[0]
+		children = children[1:]
+	}
+	return &ret, nil
+}
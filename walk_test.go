@@ -0,0 +1,121 @@
+package symdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "github.com/zenground0/symdiff"
+)
+
+type nodeCounter map[string]int
+
+func (c nodeCounter) Visit(exp PolyExp) Visitor {
+	c[exp.ToSExp().String()]++
+	// products are counted but not entered
+	if exp.IsProduct() {
+		return nil
+	}
+	return c
+}
+
+func TestWalk(t *testing.T) {
+	poly := polyFromString(t, "( + ( * 3 ( ^ x 2 ) ) ( ^ x n ) ( ^ x n ) )", "n")
+	counter := make(nodeCounter)
+	Walk(poly, counter)
+	assert.Equal(t, nodeCounter{
+		"( + ( * 3 ( ^ x 2 ) ) ( ^ x n ) ( ^ x n ) )": 1,
+		"( * 3 ( ^ x 2 ) )":                           1,
+		"( ^ x n )":                                   2,
+		"n":                                           2,
+	}, counter)
+}
+
+func TestInspectOrder(t *testing.T) {
+	poly := polyFromString(t, "( + ( * a ( ^ x 2 ) ( sqrt ( ^ x 1 ) ) ) ( abs b ) "+hinge+" )", "a", "b")
+	var visited []string
+	Inspect(poly, func(e PolyExp) bool {
+		if !e.IsSum() && !e.IsProduct() && !e.IsPiecewise() {
+			visited = append(visited, e.ToSExp().String())
+		}
+		return true
+	})
+	assert.Equal(t, []string{
+		"a",
+		"( ^ x 2 )",
+		"( ^ ( ^ x 1 ) 1/2 )",
+		"( ^ x 1 )",
+		"( abs b )",
+		"b",
+		"( ^ x 1 )",
+		"1",
+		"1",
+		"-1",
+		"( ^ x 1 )",
+		"0",
+	}, visited)
+}
+
+func TestRewriteRename(t *testing.T) {
+	raw := "( + ( * a ( ^ x n ) ) ( abs ( ^ x 1 ) ) ( ^ ( + ( ^ x 2 ) 1 ) 1/2 ) )"
+	poly := polyFromString(t, raw, "a", "n")
+	renamed, err := Rewrite(poly, func(e PolyExp) (PolyExp, bool) {
+		if m, err := e.Mon(); err == nil {
+			if x, _ := m.Term(); x == "x" {
+				y, err := SymbolicPow("y", m.Exponent())
+				require.NoError(t, err)
+				return y, true
+			}
+		}
+		return e, false
+	})
+	require.NoError(t, err)
+	// the parameter a is left alone
+	assert.Equal(t, "( + ( * a ( ^ y n ) ) ( abs ( ^ y 1 ) ) ( ^ ( + ( ^ y 2 ) 1 ) 1/2 ) )", renamed.ToSExp().String())
+	assert.Equal(t, raw, poly.ToSExp().String())
+}
+
+func TestRewriteClampDegree(t *testing.T) {
+	poly := polyFromString(t, "( + ( * 3 ( ^ x 5 ) ) ( ^ y 2 ) ( ^ x 1 ) 7 )")
+	clamped, err := Rewrite(poly, func(e PolyExp) (PolyExp, bool) {
+		if m, err := e.Mon(); err == nil {
+			if x, n := m.Term(); n > 2 {
+				ret, err := Pow(x, Integer(2))
+				return ret, err == nil
+			}
+		}
+		return e, false
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "( + ( * 3 ( ^ x 2 ) ) ( ^ y 2 ) ( ^ x 1 ) 7 )", clamped.ToSExp().String())
+}
+
+func TestRewriteUnchanged(t *testing.T) {
+	poly := polyFromString(t, "( + ( * 3 ( ^ x 5 ) ) "+hinge+" )")
+	same, err := Rewrite(poly, func(e PolyExp) (PolyExp, bool) {
+		return e, false
+	})
+	require.NoError(t, err)
+	assert.Equal(t, poly, same)
+	assert.True(t, poly.Expr() == same.Expr())
+}
+
+func TestRewriteValidates(t *testing.T) {
+	poly := polyFromString(t, "( * a ( ^ x 2 ) )", "a")
+	x, err := Var("x")
+	require.NoError(t, err)
+	toVar := func(e PolyExp) (PolyExp, bool) {
+		return x, e.IsParam()
+	}
+	_, err = Rewrite(poly, toVar)
+	assert.Error(t, err)
+
+	// symbolic exponents can't depend on their variable
+	_, err = Rewrite(polyFromString(t, "( ^ x n )", "n"), toVar)
+	assert.Error(t, err)
+
+	_, err = Rewrite(poly, func(e PolyExp) (PolyExp, bool) {
+		return PolyExp{}, true
+	})
+	assert.Error(t, err)
+}